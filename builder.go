@@ -0,0 +1,157 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"fmt"
+)
+
+// Builder creates sitemaps and sitemap indexes that share the same settings
+// and writes them to files. It is created with NewBuilder and configured
+// with options, after which it does not change.
+type Builder struct {
+	baseURL    string
+	gzipLevel  int
+	compact    bool
+	validate   bool
+	maxItems   int
+	schemes    []string
+	namespaces [][2]string
+
+	// schemesSet is set by WithAllowedSchemes, so that no schemes allows any
+	// scheme like Sitemap.SetAllowedSchemes does
+	schemesSet bool
+}
+
+// Option configures a Builder, see NewBuilder
+type Option func(*Builder)
+
+// NewBuilder returns a builder configured with opts. Without options the
+// sitemaps have no base URL, are indented, are limited to MaxSitemapItems
+// items with http and https locs and have no custom namespaces. Files are
+// gzipped with gzip.DefaultCompression and validated before they are
+// written.
+func NewBuilder(opts ...Option) *Builder {
+	b := &Builder{
+		gzipLevel: gzip.DefaultCompression,
+		validate:  true,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// WithBaseURL sets the URL relative locs are resolved against, see
+// Sitemap.SetBaseURL
+func WithBaseURL(baseURL string) Option {
+	return func(b *Builder) {
+		b.baseURL = baseURL
+	}
+}
+
+// WithGzipLevel sets the compression level of *.xml.gz files, which is one
+// of the compress/gzip levels
+func WithGzipLevel(level int) Option {
+	return func(b *Builder) {
+		b.gzipLevel = level
+	}
+}
+
+// WithCompact sets whether the output has no whitespace between the
+// elements, see Sitemap.SetCompact
+func WithCompact(compact bool) Option {
+	return func(b *Builder) {
+		b.compact = compact
+	}
+}
+
+// WithValidation sets whether sitemaps and sitemap indexes are validated
+// before they are written, so that a file that breaks the protocol is not
+// written
+func WithValidation(validate bool) Option {
+	return func(b *Builder) {
+		b.validate = validate
+	}
+}
+
+// WithMaxItems lowers the maximum number of items of a sitemap, see
+// Sitemap.SetMaxItems
+func WithMaxItems(n int) Option {
+	return func(b *Builder) {
+		b.maxItems = n
+	}
+}
+
+// WithAllowedSchemes sets the schemes locs may have, see
+// Sitemap.SetAllowedSchemes
+func WithAllowedSchemes(schemes ...string) Option {
+	return func(b *Builder) {
+		b.schemes = schemes
+		b.schemesSet = true
+	}
+}
+
+// WithNamespace declares a custom namespace, see Sitemap.AddNamespace
+func WithNamespace(prefix, uri string) Option {
+	return func(b *Builder) {
+		b.namespaces = append(b.namespaces, [2]string{prefix, uri})
+	}
+}
+
+// Sitemap returns an empty sitemap with the settings of the builder. It
+// fails if the base URL or a namespace is invalid.
+func (b *Builder) Sitemap() (*Sitemap, error) {
+	s := New()
+	if err := s.SetBaseURL(b.baseURL); err != nil {
+		return nil, err
+	}
+	for _, ns := range b.namespaces {
+		if err := s.AddNamespace(ns[0], ns[1]); err != nil {
+			return nil, err
+		}
+	}
+	if b.schemesSet {
+		s.SetAllowedSchemes(b.schemes...)
+	}
+	s.SetMaxItems(b.maxItems)
+	s.SetCompact(b.compact)
+
+	return s, nil
+}
+
+// Index returns an empty sitemap index with the settings of the builder. It
+// fails if the base URL is invalid.
+func (b *Builder) Index() (*SitemapIndex, error) {
+	s := NewSitemapIndex()
+	if err := s.SetBaseURL(b.baseURL); err != nil {
+		return nil, err
+	}
+	s.SetCompact(b.compact)
+
+	return s, nil
+}
+
+// WriteFile saves the sitemap to a file named *.xml or *.xml.gz with the
+// compression level of the builder, after validating it if validation is
+// enabled
+func (b *Builder) WriteFile(s *Sitemap, path string) error {
+	if b.validate {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("invalid sitemap %s: %w", path, err)
+		}
+	}
+
+	return s.ToFileWithLevel(path, b.gzipLevel)
+}
+
+// WriteIndexFile saves the sitemap index like WriteFile saves a sitemap
+func (b *Builder) WriteIndexFile(s *SitemapIndex, path string) error {
+	if b.validate {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("invalid sitemap index %s: %w", path, err)
+		}
+	}
+
+	return s.ToFileWithLevel(path, b.gzipLevel)
+}
@@ -0,0 +1,76 @@
+package sitemap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder(
+		WithBaseURL("http://www.google.com/"),
+		WithCompact(true),
+		WithMaxItems(1),
+		WithNamespace("custom", "http://www.google.com/custom"),
+	)
+
+	sitemap, err := b.Sitemap()
+	if err != nil {
+		t.Fatalf("could not build sitemap: %v", err)
+	}
+	if err := sitemap.Add(SitemapItem{Loc: "/about"}); err != nil {
+		t.Fatalf("could not add item: %v", err)
+	}
+	if err := sitemap.Add(SitemapItem{Loc: "/contact"}); !errors.Is(err, ErrMaxItemsExceeded) {
+		t.Errorf("Expected the item limit of the builder to apply, actual: %v", err)
+	}
+
+	str := sitemap.String()
+	if !strings.Contains(str, "<loc>http://www.google.com/about</loc>") || strings.Contains(str, "\n") || !strings.Contains(str, `xmlns:custom="http://www.google.com/custom"`) {
+		t.Errorf("Expected sitemap to have the settings of the builder, actual: %s", str)
+	}
+
+	index, err := b.Index()
+	if err != nil {
+		t.Fatalf("could not build sitemap index: %v", err)
+	}
+	index.Add(SitemapIndexItem{Loc: "sitemap.xml"})
+	if str := index.String(); !strings.Contains(str, "<loc>http://www.google.com/sitemap.xml</loc>") || strings.Contains(str, "\n") {
+		t.Errorf("Expected sitemap index to have the settings of the builder, actual: %s", str)
+	}
+
+	sitemap, err = NewBuilder(WithAllowedSchemes()).Sitemap()
+	if err != nil {
+		t.Fatalf("could not build sitemap: %v", err)
+	}
+	if err := sitemap.Add(SitemapItem{Loc: "ftp://www.google.com/file"}); err != nil {
+		t.Errorf("Expected no allowed schemes to allow any scheme, actual: %v", err)
+	}
+
+	if _, err := NewBuilder(WithBaseURL("/relative")).Sitemap(); err == nil {
+		t.Errorf("Expected a relative base URL to fail")
+	}
+}
+
+func TestBuilderWriteFile(t *testing.T) {
+	dir := t.TempDir()
+
+	invalid := New()
+	invalid.items = append(invalid.items, SitemapItem{Loc: "/relative"})
+	if err := NewBuilder().WriteFile(invalid, filepath.Join(dir, "invalid.xml")); !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected invalid sitemap not to be written, actual: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "invalid.xml")); err == nil {
+		t.Errorf("Expected no file for the invalid sitemap")
+	}
+
+	if err := NewBuilder(WithValidation(false)).WriteFile(invalid, filepath.Join(dir, "invalid.xml")); err != nil {
+		t.Errorf("Expected sitemap to be written without validation, actual: %v", err)
+	}
+
+	if err := NewBuilder(WithValidation(false), WithGzipLevel(42)).WriteFile(invalid, filepath.Join(dir, "invalid.xml.gz")); err == nil {
+		t.Errorf("Expected invalid gzip level to fail")
+	}
+}
@@ -0,0 +1,47 @@
+package sitemap
+
+import "slices"
+
+// Diff compares two versions of a sitemap by Loc. It returns the items of
+// after that are not in before, the items of before that are not in after
+// and the items of after whose LastMod differs from the item with the same
+// Loc in before. LastMod is compared at the precision it is written with,
+// see SetLastModPrecision, so that a sitemap that was parsed again does not
+// differ from the one it was written from. The items are in the order of
+// the sitemap they come from.
+func Diff(before, after *Sitemap) (added, removed, modified []SitemapItem) {
+	before.mu.Lock()
+	old := slices.Clone(before.items)
+	precision := before.precision()
+	before.mu.Unlock()
+
+	after.mu.Lock()
+	current := slices.Clone(after.items)
+	precision = max(precision, after.precision())
+	after.mu.Unlock()
+
+	oldItems := make(map[string]SitemapItem, len(old))
+	for _, item := range old {
+		oldItems[item.Loc] = item
+	}
+	currentLocs := make(map[string]struct{}, len(current))
+	for _, item := range current {
+		currentLocs[item.Loc] = struct{}{}
+
+		oldItem, ok := oldItems[item.Loc]
+		switch {
+		case !ok:
+			added = append(added, item)
+		case !oldItem.LastMod.Truncate(precision).Equal(item.LastMod.Truncate(precision)):
+			modified = append(modified, item)
+		}
+	}
+
+	for _, item := range old {
+		if _, ok := currentLocs[item.Loc]; !ok {
+			removed = append(removed, item)
+		}
+	}
+
+	return added, removed, modified
+}
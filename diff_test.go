@@ -0,0 +1,100 @@
+package sitemap
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	yesterday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	today := yesterday.AddDate(0, 0, 1)
+
+	before := New()
+	before.Add(SitemapItem{Loc: "http://www.google.com/kept", LastMod: yesterday})
+	before.Add(SitemapItem{Loc: "http://www.google.com/changed", LastMod: yesterday})
+	before.Add(SitemapItem{Loc: "http://www.google.com/removed"})
+
+	after := New()
+	after.Add(SitemapItem{Loc: "http://www.google.com/added"})
+	after.Add(SitemapItem{Loc: "http://www.google.com/changed", LastMod: today})
+	after.Add(SitemapItem{Loc: "http://www.google.com/kept", LastMod: yesterday})
+
+	added, removed, modified := Diff(before, after)
+
+	expected := []SitemapItem{{Loc: "http://www.google.com/added"}}
+	if !reflect.DeepEqual(added, expected) {
+		t.Errorf("Expected added items to be %v, actual: %v", expected, added)
+	}
+	expected = []SitemapItem{{Loc: "http://www.google.com/removed"}}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected removed items to be %v, actual: %v", expected, removed)
+	}
+	expected = []SitemapItem{{Loc: "http://www.google.com/changed", LastMod: today}}
+	if !reflect.DeepEqual(modified, expected) {
+		t.Errorf("Expected modified items to be %v, actual: %v", expected, modified)
+	}
+}
+
+func TestDiffSubSecondLastMod(t *testing.T) {
+	lastMod := time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC)
+
+	before := New()
+	before.Add(SitemapItem{Loc: "http://www.google.com", LastMod: lastMod})
+
+	after, err := Parse(strings.NewReader(before.String()))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	if strings.Contains(before.String(), ".123") {
+		t.Errorf("Expected LastMod to be written in whole seconds, actual: %s", before)
+	}
+
+	if _, _, modified := Diff(before, after); len(modified) != 0 {
+		t.Errorf("Expected no modified items, actual: %v", modified)
+	}
+
+	before.SetLastModFormat(time.RFC3339Nano)
+	before.SetLastModPrecision(time.Millisecond)
+	after, err = Parse(strings.NewReader(before.String()))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	after.SetLastModPrecision(time.Millisecond)
+	if _, _, modified := Diff(before, after); len(modified) != 0 {
+		t.Errorf("Expected no modified items at millisecond precision, actual: %v", modified)
+	}
+
+	after.Touch("http://www.google.com", lastMod.Add(time.Millisecond))
+	if _, _, modified := Diff(before, after); len(modified) != 1 {
+		t.Errorf("Expected %d modified item at millisecond precision, actual: %v", 1, modified)
+	}
+}
+
+func TestConcurrentDiff(t *testing.T) {
+	before := New()
+	after := New()
+	for i := 0; i < 100; i++ {
+		before.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", 100-i)})
+		after.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			before.Sort()
+			after.Sort()
+			before.SortByLastMod()
+			after.SortByLastMod()
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		Diff(before, after)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,111 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encoder writes a sitemap to an output stream one item at a time, so that a
+// large sitemap never has to be held in memory. Since the items are not known
+// up front, the urlset tag declares the namespaces of all supported
+// extensions.
+type Encoder struct {
+	cw     countWriter
+	zip    *gzip.Writer
+	items  int
+	closed bool
+
+	// maxItems is the maximum number of items, see SetMaxItems
+	maxItems int
+}
+
+// NewEncoder returns an encoder that writes to w. The start of the urlset is
+// written right away, an error doing so is returned by Encode or Close.
+func NewEncoder(w io.Writer) *Encoder {
+	e := &Encoder{
+		cw: countWriter{w: w},
+	}
+
+	e.cw.WriteString(urlsetStart)
+	for _, attr := range allNamespaces() {
+		e.cw.WriteString(attr)
+	}
+	e.cw.WriteString(">")
+
+	return e
+}
+
+// NewGzipEncoder returns an encoder that gzips the sitemap with the given
+// compression level, one of the compress/gzip levels, before writing it to w.
+// Close flushes the compressed data to w.
+func NewGzipEncoder(w io.Writer, level int) (*Encoder, error) {
+	zip, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+
+	e := NewEncoder(zip)
+	e.zip = zip
+
+	return e, nil
+}
+
+// SetMaxItems lowers the maximum number of items of the sitemap like
+// Sitemap.SetMaxItems does
+func (e *Encoder) SetMaxItems(n int) {
+	e.maxItems = n
+}
+
+// Encode writes item to the stream. It applies the same checks as
+// Sitemap.Add.
+func (e *Encoder) Encode(item SitemapItem) error {
+	if e.closed {
+		return errors.New("encoder is closed")
+	}
+	if e.cw.err != nil {
+		return e.cw.err
+	}
+
+	if limit := itemLimit(e.maxItems); e.items >= limit {
+		return fmt.Errorf("%w, your sitemap has reached the maximum of %d items", ErrMaxItemsExceeded, limit)
+	}
+
+	if err := item.Validate(); err != nil {
+		return err
+	}
+
+	str := item.String()
+	if e.items > 0 {
+		str = itemSeparator + str
+	}
+	if total := int(e.cw.n) + len(str) + len(sitemapFooter); total > MaxUncompressedBytes {
+		return fmt.Errorf("%w, adding %s would grow the sitemap to %d bytes, more than %d", ErrMaxSizeExceeded, item.Loc, total, MaxUncompressedBytes)
+	}
+
+	e.cw.WriteString(str)
+	e.items++
+
+	return e.cw.err
+}
+
+// Close writes the end of the urlset and, for a gzip encoder, closes the
+// gzip stream. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return errors.New("encoder is closed")
+	}
+	e.closed = true
+
+	e.cw.WriteString(sitemapFooter)
+	if e.cw.err != nil {
+		return e.cw.err
+	}
+
+	if e.zip != nil {
+		return e.zip.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+
+	sitemap := New()
+	for i := 0; i < 3; i++ {
+		item := SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i), Priority: Priority(0.5)}
+		sitemap.Add(item)
+		if err := encoder.Encode(item); err != nil {
+			t.Fatalf("could not encode item %d: %v", i, err)
+		}
+	}
+
+	if err := encoder.Encode(SitemapItem{Loc: "/relative"}); err == nil {
+		t.Errorf("Expected invalid item to be rejected")
+	}
+
+	if err := encoder.Close(); err != nil {
+		t.Fatalf("could not close encoder: %v", err)
+	}
+	if err := encoder.Encode(SitemapItem{Loc: "http://www.google.com"}); err == nil {
+		t.Errorf("Expected encoding after close to fail")
+	}
+
+	for _, namespace := range []string{ImageNamespace, VideoNamespace, NewsNamespace, XHTMLNamespace} {
+		if !strings.Contains(buf.String(), namespace) {
+			t.Errorf("Expected encoded sitemap to declare namespace %s, actual: %s", namespace, buf.String())
+		}
+	}
+
+	expected := sitemap.String()[strings.Index(sitemap.String(), "\n\t<url>"):]
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("Expected encoded sitemap to end with %s, actual: %s", expected, buf.String())
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("could not parse encoded sitemap: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.items, sitemap.items) {
+		t.Errorf("Expected encoded items to be parsed as %+v, actual: %+v", sitemap.items, parsed.items)
+	}
+}
+
+func TestGzipEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder, err := NewGzipEncoder(&buf, gzip.BestCompression)
+	if err != nil {
+		t.Fatalf("could not create gzip encoder: %v", err)
+	}
+
+	sitemap := New()
+	for i := 0; i < 3; i++ {
+		item := SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)}
+		sitemap.Add(item)
+		if err := encoder.Encode(item); err != nil {
+			t.Fatalf("could not encode item %d: %v", i, err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		t.Fatalf("could not close gzip encoder: %v", err)
+	}
+
+	zip, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("encoded sitemap is not gzipped: %v", err)
+	}
+	parsed, err := Parse(zip)
+	if err != nil {
+		t.Fatalf("could not parse gzipped sitemap: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.items, sitemap.items) {
+		t.Errorf("Expected gzipped items to be parsed as %+v, actual: %+v", sitemap.items, parsed.items)
+	}
+
+	if _, err := NewGzipEncoder(&buf, 42); err == nil {
+		t.Errorf("Expected gzip level 42 to be rejected")
+	}
+}
+
+func TestEncoderSetMaxItems(t *testing.T) {
+	e := NewEncoder(io.Discard)
+	e.SetMaxItems(1)
+
+	if err := e.Encode(SitemapItem{Loc: "http://www.google.com/a"}); err != nil {
+		t.Fatalf("could not encode item: %v", err)
+	}
+	if err := e.Encode(SitemapItem{Loc: "http://www.google.com/b"}); !errors.Is(err, ErrMaxItemsExceeded) {
+		t.Errorf("Expected encoding beyond %d item to fail, actual: %v", 1, err)
+	}
+}
@@ -0,0 +1,36 @@
+package sitemap
+
+import "errors"
+
+// Errors returned by the package, wrapped with details about the cause. Use
+// errors.Is to check for them.
+var (
+	// ErrMaxItemsExceeded is returned when a sitemap or sitemap index would
+	// get more items than it may have
+	ErrMaxItemsExceeded = errors.New("maximum number of items exceeded")
+
+	// ErrMaxSizeExceeded is returned when a sitemap would get bigger than the
+	// maximum size of an uncompressed sitemap
+	ErrMaxSizeExceeded = errors.New("maximum size exceeded")
+
+	// ErrInvalidLoc is returned for a loc that is too long or not an
+	// absolute URL
+	ErrInvalidLoc = errors.New("invalid loc")
+
+	// ErrInvalidPriority is returned for a priority outside [0.0, 1.0]
+	ErrInvalidPriority = errors.New("invalid priority")
+
+	// ErrInvalidChangeFreq is returned for a changefreq that is not one of
+	// ChangeFreqs
+	ErrInvalidChangeFreq = errors.New("invalid changefreq")
+
+	// ErrInvalidExtension is returned for a filename that does not end in
+	// .xml or .xml.gz
+	ErrInvalidExtension = errors.New("invalid filename extension")
+
+	// ErrEmptySitemap is returned for a sitemap without items
+	ErrEmptySitemap = errors.New("sitemap has no items")
+
+	// ErrFutureLastMod is returned by Validate for a lastmod in the future
+	ErrFutureLastMod = errors.New("lastmod is in the future")
+)
@@ -0,0 +1,33 @@
+package sitemap
+
+import (
+	"errors"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestErrors(t *testing.T) {
+	full := New()
+	full.SetMaxItems(1)
+	full.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	future := New()
+	future.Add(SitemapItem{Loc: "http://www.google.com", LastMod: time.Now().AddDate(1, 0, 0)})
+
+	tests := map[error]error{
+		ErrMaxItemsExceeded:  full.Add(SitemapItem{Loc: "http://www.google.com/a"}),
+		ErrInvalidLoc:        New().Add(SitemapItem{Loc: "/relative"}),
+		ErrInvalidPriority:   New().Add(SitemapItem{Loc: "http://www.google.com", Priority: Priority(1.5)}),
+		ErrInvalidChangeFreq: New().Add(SitemapItem{Loc: "http://www.google.com", ChangeFreq: "dialy"}),
+		ErrInvalidExtension:  full.ToFile(path.Join(t.TempDir(), "sitemap.gz")),
+		ErrEmptySitemap:      New().ToFile(path.Join(t.TempDir(), "sitemap.xml")),
+		ErrFutureLastMod:     future.Validate(),
+	}
+
+	for target, err := range tests {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected error to be %v, actual: %v", target, err)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// ImageNamespace is the XML namespace of the image sitemap extension
+	ImageNamespace = "http://www.google.com/schemas/sitemap-image/1.1"
+
+	// VideoNamespace is the XML namespace of the video sitemap extension
+	VideoNamespace = "http://www.google.com/schemas/sitemap-video/1.1"
+
+	// NewsNamespace is the XML namespace of the news sitemap extension
+	NewsNamespace = "http://www.google.com/schemas/sitemap-news/0.9"
+
+	// XHTMLNamespace is the XML namespace of the xhtml:link elements used for
+	// alternate language versions
+	XHTMLNamespace = "http://www.w3.org/1999/xhtml"
+
+	// MobileNamespace is the XML namespace of the mobile sitemap extension
+	MobileNamespace = "http://www.google.com/schemas/sitemap-mobile/1.0"
+
+	// GeoNamespace is the XML namespace of the geo sitemap extension
+	GeoNamespace = "http://www.google.com/geo/schemas/sitemap/1.0"
+)
+
+// Image is an image on the page of a sitemap item. Only Loc is required.
+// See https://developers.google.com/search/docs/crawling-indexing/sitemaps/image-sitemaps
+type Image struct {
+	Loc         string `xml:"image:loc"`
+	Caption     string `xml:"image:caption,omitempty"`
+	GeoLocation string `xml:"image:geo_location,omitempty"`
+	Title       string `xml:"image:title,omitempty"`
+	License     string `xml:"image:license,omitempty"`
+}
+
+// Video is a video on the page of a sitemap item. Title and Description are
+// required, Duration is rounded down to whole seconds.
+// See https://developers.google.com/search/docs/crawling-indexing/sitemaps/video-sitemaps
+type Video struct {
+	ThumbnailLoc    string        `xml:"video:thumbnail_loc,omitempty"`
+	Title           string        `xml:"video:title"`
+	Description     string        `xml:"video:description"`
+	ContentLoc      string        `xml:"video:content_loc,omitempty"`
+	PlayerLoc       string        `xml:"video:player_loc,omitempty"`
+	Duration        time.Duration `xml:"video:duration,omitempty"`
+	PublicationDate time.Time     `xml:"video:publication_date,omitempty"`
+}
+
+// MarshalXML encodes the video as a video:video element. It implements
+// xml.Marshaler to write Duration in seconds and to leave out an unset
+// PublicationDate.
+func (v Video) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(v.wire(), start)
+}
+
+// validate checks that the required fields of the video are set
+func (v *Video) validate() error {
+	if v.Title == "" {
+		return errors.New("video has no title")
+	}
+	if v.Description == "" {
+		return fmt.Errorf("video %q has no description", v.Title)
+	}
+
+	return nil
+}
+
+// NewsInfo describes the news article on the page of a sitemap item. All
+// fields are required.
+// See https://developers.google.com/search/docs/crawling-indexing/sitemaps/news-sitemap
+type NewsInfo struct {
+	PublicationName     string    `xml:"news:publication>news:name"`
+	PublicationLanguage string    `xml:"news:publication>news:language"`
+	PublicationDate     time.Time `xml:"news:publication_date"`
+	Title               string    `xml:"news:title"`
+}
+
+// MarshalXML encodes the news info as a news:news element. It implements
+// xml.Marshaler to format PublicationDate the way the protocol expects it.
+func (n NewsInfo) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(n.wire(), start)
+}
+
+// validate checks that all fields of the news info are set
+func (n *NewsInfo) validate() error {
+	switch {
+	case n.PublicationName == "":
+		return errors.New("news has no publication name")
+	case n.PublicationLanguage == "":
+		return errors.New("news has no publication language")
+	case n.PublicationDate.IsZero():
+		return errors.New("news has no publication date")
+	case n.Title == "":
+		return errors.New("news has no title")
+	}
+
+	return nil
+}
+
+// GeoInfo marks the page as geo content, like a KML file. Format is one of
+// kml, kmz and georss.
+type GeoInfo struct {
+	Format string `xml:"geo:format"`
+}
+
+// validate checks that the format of the geo content is known
+func (g *GeoInfo) validate() error {
+	switch g.Format {
+	case "kml", "kmz", "georss":
+		return nil
+	}
+
+	return fmt.Errorf("geo format %q is not one of kml, kmz and georss", g.Format)
+}
+
+// Alternate is a version of the page of a sitemap item in another language
+// or for another region. The page should list itself among its alternates.
+// See https://developers.google.com/search/docs/specialty/international/localized-versions#sitemap
+type Alternate struct {
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// MarshalXML encodes the alternate as an xhtml:link element. It implements
+// xml.Marshaler to add the rel attribute.
+func (a Alternate) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(a.wire(), start)
+}
+
+// hreflangPattern matches a language tag of a language, optionally with a
+// script and a region, like en, zh-Hant or es-419. Full BCP 47 allows more,
+// but search engines do not.
+var hreflangPattern = regexp.MustCompile(`^(?i)[a-z]{2,3}(-[a-z]{4})?(-([a-z]{2}|[0-9]{3}))?$`)
+
+// validate checks that Hreflang is a language tag or x-default
+func (a *Alternate) validate() error {
+	if a.Hreflang == "x-default" || hreflangPattern.MatchString(a.Hreflang) {
+		return nil
+	}
+
+	return fmt.Errorf("hreflang %q of %s is not a language tag like en or en-US", a.Hreflang, a.Href)
+}
+
+// reservedPrefixes are the namespace prefixes declared by the package
+var reservedPrefixes = []string{"xml", "xmlns", "xsi", "image", "video", "news", "xhtml", "mobile", "geo"}
+
+// AddNamespace declares the namespace uri with the given prefix on the urlset
+// tag, for extensions the package does not support. Adding a prefix again
+// replaces its namespace. The prefixes of the supported extensions are
+// reserved.
+func (s *Sitemap) AddNamespace(prefix, uri string) error {
+	if !validPrefix(prefix) {
+		return fmt.Errorf("namespace prefix %q is not a valid XML name", prefix)
+	}
+	if slices.Contains(reservedPrefixes, strings.ToLower(prefix)) {
+		return fmt.Errorf("namespace prefix %q is reserved", prefix)
+	}
+	if uri == "" {
+		return fmt.Errorf("namespace %q has no URI", prefix)
+	}
+
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(uri))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.customNamespaces == nil {
+		s.customNamespaces = make(map[string]string)
+	}
+	s.customNamespaces[prefix] = xmlns(prefix, escaped.String())
+
+	return nil
+}
+
+// validPrefix reports whether prefix can be used as a namespace prefix
+func validPrefix(prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+
+	for i, r := range prefix {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// extensions is a set of the extensions that items use
+type extensions uint8
+
+// The extensions an item can use
+const (
+	extImage extensions = 1 << iota
+	extVideo
+	extNews
+	extXHTML
+	extMobile
+	extGeo
+)
+
+// extensions returns the extensions the item uses
+func (i *SitemapItem) extensions() extensions {
+	var used extensions
+	if len(i.Images) > 0 {
+		used |= extImage
+	}
+	if len(i.Videos) > 0 {
+		used |= extVideo
+	}
+	if i.News != nil {
+		used |= extNews
+	}
+	if len(i.Alternates) > 0 {
+		used |= extXHTML
+	}
+	if i.Mobile {
+		used |= extMobile
+	}
+	if i.Geo != nil {
+		used |= extGeo
+	}
+
+	return used
+}
+
+// namespaces returns the xmlns attributes of the extensions used by the
+// items of the sitemap and of the namespaces added with AddNamespace, so that
+// they can be added to the urlset tag. s.mu must be held.
+func (s *Sitemap) namespaces() []string {
+	var used extensions
+	for _, item := range s.items {
+		used |= item.extensions()
+	}
+
+	return s.namespacesOf(used)
+}
+
+// namespacesSize returns the size of the xmlns attributes of the used
+// extensions and the namespaces added with AddNamespace. s.mu must be held.
+func (s *Sitemap) namespacesSize(used extensions) int {
+	size := 0
+	for _, attr := range s.namespacesOf(used) {
+		size += len(s.whitespace(attr))
+	}
+
+	return size
+}
+
+// namespacesOf returns the xmlns attributes of the used extensions and of
+// the namespaces added with AddNamespace. s.mu must be held.
+func (s *Sitemap) namespacesOf(used extensions) []string {
+	var attrs []string
+	if used&extImage != 0 {
+		attrs = append(attrs, xmlns("image", ImageNamespace))
+	}
+	if used&extVideo != 0 {
+		attrs = append(attrs, xmlns("video", VideoNamespace))
+	}
+	if used&extNews != 0 {
+		attrs = append(attrs, xmlns("news", NewsNamespace))
+	}
+	if used&extXHTML != 0 {
+		attrs = append(attrs, xmlns("xhtml", XHTMLNamespace))
+	}
+	if used&extMobile != 0 {
+		attrs = append(attrs, xmlns("mobile", MobileNamespace))
+	}
+	if used&extGeo != 0 {
+		attrs = append(attrs, xmlns("geo", GeoNamespace))
+	}
+	for _, prefix := range slices.Sorted(maps.Keys(s.customNamespaces)) {
+		attrs = append(attrs, s.customNamespaces[prefix])
+	}
+
+	return attrs
+}
+
+// allNamespaces returns the xmlns attributes of all supported extensions, for
+// when the items are not known up front
+func allNamespaces() []string {
+	return []string{
+		xmlns("image", ImageNamespace),
+		xmlns("video", VideoNamespace),
+		xmlns("news", NewsNamespace),
+		xmlns("xhtml", XHTMLNamespace),
+		xmlns("mobile", MobileNamespace),
+		xmlns("geo", GeoNamespace),
+	}
+}
+
+// xmlns returns the attribute declaring the namespace with the given prefix
+func xmlns(prefix, namespace string) string {
+	return "\n\txmlns:" + prefix + `="` + namespace + `"`
+}
@@ -0,0 +1,316 @@
+package sitemap
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImages(t *testing.T) {
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	sitemap.Add(SitemapItem{
+		Loc: "http://www.google.com/gallery",
+		Images: []Image{
+			{Loc: "http://www.google.com/a.jpg"},
+			{
+				Loc:         "http://www.google.com/b.jpg",
+				Caption:     "Cats & dogs",
+				GeoLocation: "Stockholm, Sweden",
+				Title:       "B",
+				License:     "http://www.google.com/license",
+			},
+		},
+	})
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
+	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+	<url>
+		<loc>http://www.google.com</loc>
+	</url>
+
+	<url>
+		<loc>http://www.google.com/gallery</loc>
+		<image:image>
+			<image:loc>http://www.google.com/a.jpg</image:loc>
+		</image:image>
+		<image:image>
+			<image:loc>http://www.google.com/b.jpg</image:loc>
+			<image:caption>Cats &amp; dogs</image:caption>
+			<image:geo_location>Stockholm, Sweden</image:geo_location>
+			<image:title>B</image:title>
+			<image:license>http://www.google.com/license</image:license>
+		</image:image>
+	</url>
+</urlset>`
+
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap with images to be %s, actual: %s", expected, sitemap.String())
+	}
+}
+
+func TestVideos(t *testing.T) {
+	publicationDate, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	item := SitemapItem{
+		Loc: "http://www.google.com/videos",
+		Videos: []Video{
+			{
+				ThumbnailLoc:    "http://www.google.com/thumb.jpg",
+				Title:           "Grilling steaks",
+				Description:     "Alkis shows you how to get perfectly done steaks every time",
+				ContentLoc:      "http://www.google.com/video.mp4",
+				PlayerLoc:       "http://www.google.com/player?video=123&autoplay=1",
+				Duration:        600 * time.Second,
+				PublicationDate: publicationDate,
+			},
+		},
+	}
+
+	expected := `
+	<url>
+		<loc>http://www.google.com/videos</loc>
+		<video:video>
+			<video:thumbnail_loc>http://www.google.com/thumb.jpg</video:thumbnail_loc>
+			<video:title>Grilling steaks</video:title>
+			<video:description>Alkis shows you how to get perfectly done steaks every time</video:description>
+			<video:content_loc>http://www.google.com/video.mp4</video:content_loc>
+			<video:player_loc>http://www.google.com/player?video=123&amp;autoplay=1</video:player_loc>
+			<video:duration>600</video:duration>
+			<video:publication_date>2014-03-31T15:00:00+01:00</video:publication_date>
+		</video:video>
+	</url>`
+
+	if item.String() != expected {
+		t.Errorf("Expected sitemap item with video to be %s, actual: %s", expected, item.String())
+	}
+
+	sitemap := Sitemap{}
+	if err := sitemap.Add(item); err != nil {
+		t.Fatalf("could not add item with video: %v", err)
+	}
+	if !strings.Contains(sitemap.String(), `xmlns:video="`+VideoNamespace+`"`) {
+		t.Errorf("Expected sitemap with videos to declare the video namespace, actual: %s", sitemap.String())
+	}
+
+	for _, video := range []Video{
+		{Description: "No title"},
+		{Title: "No description"},
+	} {
+		err := sitemap.Add(SitemapItem{Loc: "http://www.google.com/invalid", Videos: []Video{video}})
+		if err == nil {
+			t.Errorf("Expected video %+v to be rejected", video)
+		}
+	}
+}
+
+func TestNews(t *testing.T) {
+	publicationDate, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	item := SitemapItem{
+		Loc:        "http://www.google.com/news/article",
+		ChangeFreq: ChangeFreqHourly,
+		Priority:   Priority(0.5),
+		News: &NewsInfo{
+			PublicationName:     "The Example Times",
+			PublicationLanguage: "en",
+			PublicationDate:     publicationDate,
+			Title:               "Companies A, B in merger talks",
+		},
+	}
+
+	expected := `
+	<url>
+		<loc>http://www.google.com/news/article</loc>
+		<news:news>
+			<news:publication>
+				<news:name>The Example Times</news:name>
+				<news:language>en</news:language>
+			</news:publication>
+			<news:publication_date>2014-03-31T15:00:00+01:00</news:publication_date>
+			<news:title>Companies A, B in merger talks</news:title>
+		</news:news>
+	</url>`
+
+	if item.String() != expected {
+		t.Errorf("Expected sitemap item with news to be %s, actual: %s", expected, item.String())
+	}
+
+	sitemap := Sitemap{}
+	if err := sitemap.Add(item); err != nil {
+		t.Fatalf("could not add item with news: %v", err)
+	}
+	if !strings.Contains(sitemap.String(), `xmlns:news="`+NewsNamespace+`"`) {
+		t.Errorf("Expected sitemap with news to declare the news namespace, actual: %s", sitemap.String())
+	}
+
+	err := sitemap.Add(SitemapItem{Loc: "http://www.google.com/news/invalid", News: &NewsInfo{Title: "No publication"}})
+	if err == nil {
+		t.Errorf("Expected incomplete news to be rejected")
+	}
+}
+
+func TestAlternates(t *testing.T) {
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{
+		Loc: "http://www.google.com/en/",
+		Alternates: []Alternate{
+			{Hreflang: "en", Href: "http://www.google.com/en/"},
+			{Hreflang: "de", Href: "http://www.google.com/de/"},
+			{Hreflang: "x-default", Href: "http://www.google.com/?lang=en&region=us"},
+		},
+	})
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
+	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:xhtml="http://www.w3.org/1999/xhtml">
+	<url>
+		<loc>http://www.google.com/en/</loc>
+		<xhtml:link rel="alternate" hreflang="en" href="http://www.google.com/en/"></xhtml:link>
+		<xhtml:link rel="alternate" hreflang="de" href="http://www.google.com/de/"></xhtml:link>
+		<xhtml:link rel="alternate" hreflang="x-default" href="http://www.google.com/?lang=en&amp;region=us"></xhtml:link>
+	</url>
+</urlset>`
+
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap with alternates to be %s, actual: %s", expected, sitemap.String())
+	}
+}
+
+func TestMobile(t *testing.T) {
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/amp/", Mobile: true})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/"})
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
+	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:mobile="http://www.google.com/schemas/sitemap-mobile/1.0">
+	<url>
+		<loc>http://www.google.com/amp/</loc>
+		<mobile:mobile></mobile:mobile>
+	</url>
+
+	<url>
+		<loc>http://www.google.com/</loc>
+	</url>
+</urlset>`
+
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap with mobile item to be %s, actual: %s", expected, sitemap.String())
+	}
+
+	sitemap = Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/"})
+	if strings.Contains(sitemap.String(), "mobile") {
+		t.Errorf("Expected sitemap without mobile items to have no mobile namespace, actual: %s", sitemap.String())
+	}
+}
+
+func TestAddNamespace(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/"})
+	plain := sitemap.String()
+
+	if err := sitemap.AddNamespace("pagemap", "http://www.google.com/schemas/sitemap-pagemap/1.0"); err != nil {
+		t.Fatalf("could not add namespace: %v", err)
+	}
+	if err := sitemap.AddNamespace("custom", "http://example.com/ns?a=1&b=2"); err != nil {
+		t.Fatalf("could not add namespace: %v", err)
+	}
+
+	expected := strings.Replace(plain, `xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`, `xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:custom="http://example.com/ns?a=1&amp;b=2"
+	xmlns:pagemap="http://www.google.com/schemas/sitemap-pagemap/1.0">`, 1)
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap with namespaces to be %s, actual: %s", expected, sitemap.String())
+	}
+	if sitemap.Size() != len(expected) {
+		t.Errorf("Expected size of sitemap with namespaces to be %d, actual: %d", len(expected), sitemap.Size())
+	}
+
+	for _, prefix := range []string{"", "image", "xsi", "1st", "a b", `a"b`} {
+		if err := sitemap.AddNamespace(prefix, "http://example.com/ns"); err == nil {
+			t.Errorf("Expected adding namespace with prefix %q to fail", prefix)
+		}
+	}
+	if err := sitemap.AddNamespace("empty", ""); err == nil {
+		t.Errorf("Expected adding namespace without URI to fail")
+	}
+}
+
+func TestGeo(t *testing.T) {
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/stores.kml", Geo: &GeoInfo{Format: "kml"}})
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
+	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:geo="http://www.google.com/geo/schemas/sitemap/1.0">
+	<url>
+		<loc>http://www.google.com/stores.kml</loc>
+		<geo:geo>
+			<geo:format>kml</geo:format>
+		</geo:geo>
+	</url>
+</urlset>`
+
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap with geo to be %s, actual: %s", expected, sitemap.String())
+	}
+
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com/stores.csv", Geo: &GeoInfo{Format: "csv"}}); err == nil {
+		t.Errorf("Expected adding geo content with an unknown format to fail")
+	}
+}
+
+func TestExtra(t *testing.T) {
+	sitemap := New()
+	sitemap.AddNamespace("pagemap", "http://www.google.com/schemas/sitemap-pagemap/1.0")
+	sitemap.Add(SitemapItem{
+		Loc:      "http://www.google.com/",
+		Priority: Priority(0.5),
+		Extra:    `<pagemap:PageMap><pagemap:DataObject type="document"></pagemap:DataObject></pagemap:PageMap>`,
+	})
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
+	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:pagemap="http://www.google.com/schemas/sitemap-pagemap/1.0">
+	<url>
+		<loc>http://www.google.com/</loc>
+		<priority>0.5</priority><pagemap:PageMap><pagemap:DataObject type="document"></pagemap:DataObject></pagemap:PageMap>
+	</url>
+</urlset>`
+
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap with extra XML to be %s, actual: %s", expected, sitemap.String())
+	}
+}
+
+func TestAlternateHreflang(t *testing.T) {
+	valid := []string{"en", "en-US", "en-us", "zh-Hant", "zh-Hant-TW", "es-419", "x-default"}
+	for _, hreflang := range valid {
+		item := SitemapItem{Loc: "http://www.google.com", Alternates: []Alternate{{Hreflang: hreflang, Href: "http://www.google.com"}}}
+		if err := item.Validate(); err != nil {
+			t.Errorf("Expected hreflang %q to be valid, actual: %v", hreflang, err)
+		}
+	}
+
+	invalid := []string{"", "english", "en_US", "en-USA", "x-default-en"}
+	for _, hreflang := range invalid {
+		item := SitemapItem{Loc: "http://www.google.com", Alternates: []Alternate{{Hreflang: hreflang, Href: "http://www.google.com/en/"}}}
+		err := New().Add(item)
+		if err == nil || !strings.Contains(err.Error(), "http://www.google.com/en/") {
+			t.Errorf("Expected hreflang %q to be reported with its href, actual: %v", hreflang, err)
+		}
+	}
+}
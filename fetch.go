@@ -0,0 +1,118 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Fetch downloads and parses the sitemap at url with client, or HTTPClient
+// if client is nil. Both gzipped files and gzip content encoding are
+// handled. The Last-Modified header of the response is available from
+// LastModified of the sitemap. The items are kept as they are, so that a
+// sitemap that breaks the protocol can still be read, use Validate to check
+// it.
+func Fetch(ctx context.Context, url string, client *http.Client) (*Sitemap, error) {
+	resp, err := fetch(ctx, url, client)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	s, err := parseSitemap(resp.Body, false, func(s *Sitemap, item SitemapItem) error {
+		s.push(item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sitemap %s: %v", url, err)
+	}
+
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		s.lastModified, _ = http.ParseTime(lastModified)
+	}
+
+	return s, nil
+}
+
+// FetchIndex downloads and parses the sitemap index at url like Fetch
+func FetchIndex(ctx context.Context, url string, client *http.Client) (*SitemapIndex, error) {
+	resp, err := fetch(ctx, url, client)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	s, err := ParseIndex(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sitemap index %s: %v", url, err)
+	}
+
+	return s, nil
+}
+
+// fetchWorkers is the number of sitemaps ResolveAll fetches at the same time
+const fetchWorkers = 8
+
+// ResolveAll fetches all sitemaps in the index with client, or HTTPClient if
+// client is nil, and returns their items in the order of the index. The
+// sitemaps are fetched concurrently. A sitemap that can not be fetched does
+// not stop the others, the returned error combines the errors of all
+// sitemaps that failed.
+func (s *SitemapIndex) ResolveAll(ctx context.Context, client *http.Client) ([]SitemapItem, error) {
+	results := make([][]SitemapItem, len(s.items))
+	errs := make([]error, len(s.items))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchWorkers)
+	for i, item := range s.items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, loc string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			sitemap, err := Fetch(ctx, loc, client)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			results[i] = sitemap.items
+		}(i, item.Loc)
+	}
+	wg.Wait()
+
+	var items []SitemapItem
+	for _, result := range results {
+		items = append(items, result...)
+	}
+
+	return items, errors.Join(errs...)
+}
+
+// fetch sends a GET request for url and returns the response if it was
+// successful. Parse and ParseIndex take care of any gzip content encoding.
+func fetch(ctx context.Context, url string, client *http.Client) (*http.Response, error) {
+	if client == nil {
+		client = HTTPClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not fetch %s: %s", url, resp.Status)
+	}
+
+	return resp, nil
+}
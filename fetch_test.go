@@ -0,0 +1,162 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", Priority: Priority(0.5)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/about"})
+
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml.gz"})
+
+	gzipped := func(b []byte) []byte {
+		var buf bytes.Buffer
+		zip := gzip.NewWriter(&buf)
+		zip.Write(b)
+		zip.Close()
+		return buf.Bytes()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Write([]byte(sitemap.String()))
+		case "/sitemap.xml.gz":
+			w.Write(gzipped([]byte(sitemap.String())))
+		case "/encoded.xml":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped([]byte(sitemap.String())))
+		case "/encoded.xml.gz":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped(gzipped([]byte(sitemap.String()))))
+		case "/sitemap-index.xml":
+			w.Write([]byte(sitemapIndex.String()))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"/sitemap.xml", "/sitemap.xml.gz", "/encoded.xml", "/encoded.xml.gz"} {
+		fetched, err := Fetch(context.Background(), server.URL+name, server.Client())
+		if err != nil {
+			t.Errorf("could not fetch %s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(fetched.items, sitemap.items) {
+			t.Errorf("Expected %s to be fetched as %+v, actual: %+v", name, sitemap.items, fetched.items)
+		}
+	}
+
+	fetchedIndex, err := FetchIndex(context.Background(), server.URL+"/sitemap-index.xml", nil)
+	if err != nil {
+		t.Fatalf("could not fetch sitemap index: %v", err)
+	}
+	if !reflect.DeepEqual(fetchedIndex.items, sitemapIndex.items) {
+		t.Errorf("Expected sitemap index to be fetched as %+v, actual: %+v", sitemapIndex.items, fetchedIndex.items)
+	}
+
+	if _, err := Fetch(context.Background(), server.URL+"/missing.xml", nil); err == nil {
+		t.Errorf("Expected fetching a missing sitemap to fail")
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.xml" {
+			http.NotFound(w, r)
+			return
+		}
+
+		sitemap := New()
+		sitemap.Add(SitemapItem{Loc: server.URL + r.URL.Path + "/a"})
+		sitemap.Add(SitemapItem{Loc: server.URL + r.URL.Path + "/b"})
+		w.Write([]byte(sitemap.String()))
+	}))
+	defer server.Close()
+
+	sitemapIndex := NewSitemapIndex()
+	var expected []SitemapItem
+	for _, name := range []string{"/1.xml", "/missing.xml", "/2.xml", "/3.xml", "/4.xml", "/5.xml", "/6.xml", "/7.xml", "/8.xml", "/9.xml"} {
+		sitemapIndex.Add(SitemapIndexItem{Loc: server.URL + name})
+		if name != "/missing.xml" {
+			expected = append(expected, SitemapItem{Loc: server.URL + name + "/a"}, SitemapItem{Loc: server.URL + name + "/b"})
+		}
+	}
+
+	items, err := sitemapIndex.ResolveAll(context.Background(), server.Client())
+	if err == nil {
+		t.Errorf("Expected error for the missing sitemap")
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Expected resolved items to be %+v, actual: %+v", expected, items)
+	}
+}
+
+func TestFetchLastModified(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dated.xml" {
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		}
+		w.Write([]byte(sitemap.String()))
+	}))
+	defer server.Close()
+
+	fetched, err := Fetch(context.Background(), server.URL+"/dated.xml", server.Client())
+	if err != nil {
+		t.Fatalf("could not fetch sitemap: %v", err)
+	}
+	if !fetched.LastModified().Equal(lastModified) {
+		t.Errorf("Expected last modified to be %v, actual: %v", lastModified, fetched.LastModified())
+	}
+
+	fetched, err = Fetch(context.Background(), server.URL+"/undated.xml", server.Client())
+	if err != nil {
+		t.Fatalf("could not fetch sitemap: %v", err)
+	}
+	if !fetched.LastModified().IsZero() {
+		t.Errorf("Expected last modified without header to be zero, actual: %v", fetched.LastModified())
+	}
+}
+
+func TestFetchInvalid(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>/relative</loc></url>`)
+	for i := range MaxSitemapItems {
+		fmt.Fprintf(&b, "<url><loc>http://www.google.com/%d</loc></url>", i)
+	}
+	b.WriteString("</urlset>")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(b.String()))
+	}))
+	defer server.Close()
+
+	fetched, err := Fetch(context.Background(), server.URL+"/sitemap.xml", server.Client())
+	if err != nil {
+		t.Fatalf("could not fetch sitemap: %v", err)
+	}
+	if fetched.Len() != MaxSitemapItems+1 {
+		t.Errorf("Expected fetched sitemap to have %d items, actual: %d", MaxSitemapItems+1, fetched.Len())
+	}
+	if err := fetched.Validate(); err == nil {
+		t.Errorf("Expected fetched sitemap to be invalid")
+	}
+}
@@ -0,0 +1,277 @@
+package sitemap
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WriteFS is a filesystem that files can be created in, like the OS
+// filesystem, an in-memory one or cloud storage
+type WriteFS interface {
+	// Create creates or truncates the named file for writing
+	Create(name string) (io.WriteCloser, error)
+}
+
+// osFS is the OS filesystem
+type osFS struct{}
+
+// Create creates a temporary file next to the named file, which replaces
+// the named file once it is closed. A file that is served while it is
+// written is then always complete, either the old or the new version. A new
+// file gets the mode os.Create gives it, a replaced file keeps its mode.
+func (osFS) Create(name string) (io.WriteCloser, error) {
+	dir, base := filepath.Dir(name), filepath.Base(name)
+	file, err := createTemp(dir, base)
+	if err != nil {
+		if _, serr := os.Stat(dir); errors.Is(serr, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not create %s because the directory %s does not exist, create it first: %w", name, dir, err)
+		}
+
+		return nil, fmt.Errorf("could not create %s: %w", name, err)
+	}
+
+	if info, err := os.Stat(name); err == nil {
+		if err := file.Chmod(info.Mode().Perm()); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+
+	return &atomicFile{File: file, name: name}, nil
+}
+
+// createTemp creates a new temporary file for base in dir like
+// os.CreateTemp, but with mode 0666 before the umask like os.Create instead
+// of 0600
+func createTemp(dir, base string) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".tmp")
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrExist) && try < 10000 {
+			continue
+		}
+
+		return file, err
+	}
+}
+
+// atomicFile is a temporary file that is renamed to name when it is closed
+type atomicFile struct {
+	*os.File
+	name string
+}
+
+// Close closes the temporary file and renames it to the name of the file
+func (f *atomicFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+
+	if err := os.Rename(f.File.Name(), f.name); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+
+	return nil
+}
+
+// abort closes and removes the temporary file, leaving the named file as it
+// was
+func (f *atomicFile) abort() {
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
+
+// closeOrAbort closes file if err is nil and returns the error of closing
+// it. Otherwise a file that supports it is discarded instead, so that a
+// failed write does not replace a complete file, and err is returned.
+func closeOrAbort(file io.WriteCloser, err error) error {
+	if f, ok := file.(interface{ abort() }); ok && err != nil {
+		f.abort()
+		return err
+	}
+
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// writeFile writes the output of src to a file named *.xml or *.xml.gz. A
+// *.xml.gz file is gzipped with the given level.
+// Writing stops with the error of ctx once it is done.
+func writeFile(ctx context.Context, path string, src io.WriterTo, level int) error {
+	return writeFS(ctx, osFS{}, path, src, level)
+}
+
+// writeFS is like writeFile but creates the file in fsys
+func writeFS(ctx context.Context, fsys WriteFS, name string, src io.WriterTo, level int) error {
+	gzipped, err := isGzipName(name)
+	if err != nil {
+		return err
+	}
+
+	return saveFile(ctx, fsys, name, src, gzipped, level)
+}
+
+// isGzipName reports whether a file named *.xml.gz should be gzipped, or
+// returns an error if the name does not end in .xml or .xml.gz
+func isGzipName(name string) (bool, error) {
+	gzipped := strings.HasSuffix(name, ".xml.gz")
+	if !gzipped && !strings.HasSuffix(name, ".xml") {
+		return false, fmt.Errorf("%w of %s, it must end in .xml, or in .xml.gz to be gzipped", ErrInvalidExtension, name)
+	}
+
+	return gzipped, nil
+}
+
+// saveFile writes the output of src to the file name in fsys, gzipped with
+// the given level if gzipped is set, whatever the name of the file
+func saveFile(ctx context.Context, fsys WriteFS, name string, src io.WriterTo, gzipped bool, level int) (err error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return fmt.Errorf("gzip compression level %d is not between %d and %d", level, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	file, err := fsys.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = closeOrAbort(file, err)
+	}()
+
+	w := &contextWriter{ctx, file}
+
+	// Gzip
+	if gzipped {
+		zip, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return err
+		}
+		if _, err = src.WriteTo(&contextWriter{ctx, zip}); err != nil {
+			return err
+		}
+
+		return zip.Close()
+	}
+
+	_, err = src.WriteTo(w)
+	return err
+}
+
+// contextWriter fails writes with the error of ctx once it is done
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// Write writes p to the underlying writer unless ctx is done
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.w.Write(p)
+}
+
+// writeWorkers is the number of files WriteAll writes at the same time
+const writeWorkers = 8
+
+// WriteAll saves every sitemap to the path it is keyed by, like ToFile. The
+// files are written concurrently. A file that can not be written does not
+// stop the others, the returned error combines the errors of all files that
+// failed, in the order of their paths.
+func WriteAll(sitemaps map[string]*Sitemap) error {
+	paths := slices.Sorted(maps.Keys(sitemaps))
+	errs := make([]error, len(paths))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, writeWorkers)
+	for i, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := sitemaps[path].ToFile(path); err != nil {
+				errs[i] = fmt.Errorf("could not write %s: %w", path, err)
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// zipIndexName is the name of the sitemap index in archives written by
+// WriteZip
+const zipIndexName = "sitemap-index.xml"
+
+// WriteZip writes a zip archive to w with every sitemap in a file named by
+// its key, which ends in .xml or .xml.gz like the paths of ToFile, and the
+// index in sitemap-index.xml unless it is nil. The sitemaps are written in
+// the order of their names, after the index.
+func WriteZip(w io.Writer, index *SitemapIndex, sitemaps map[string]*Sitemap) error {
+	archive := zip.NewWriter(w)
+	fsys := zipFS{archive}
+
+	if index != nil {
+		if err := index.WriteToFS(fsys, zipIndexName); err != nil {
+			return fmt.Errorf("could not write %s: %w", zipIndexName, err)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(sitemaps)) {
+		if err := sitemaps[name].WriteToFS(fsys, name); err != nil {
+			return fmt.Errorf("could not write %s: %w", name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// zipFS creates files in a zip archive
+type zipFS struct {
+	archive *zip.Writer
+}
+
+// Create adds the named file to the archive. The file must be written
+// before the next one is created.
+func (z zipFS) Create(name string) (io.WriteCloser, error) {
+	w, err := z.archive.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nopWriteCloser{w}, nil
+}
+
+// nopWriteCloser is a writer with a Close method that does nothing
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopWriteCloser) Close() error {
+	return nil
+}
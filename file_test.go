@@ -0,0 +1,227 @@
+package sitemap
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memFS is an in-memory WriteFS
+type memFS map[string]*bytes.Buffer
+
+func (m memFS) Create(name string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	m[name] = buf
+	return nopWriteCloser{buf}, nil
+}
+
+func TestWriteToFS(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	fsys := memFS{}
+	if err := sitemap.WriteToFS(fsys, "sitemap.xml"); err != nil {
+		t.Fatalf("could not write sitemap to fs: %v", err)
+	}
+	if fsys["sitemap.xml"].String() != sitemap.String() {
+		t.Errorf("Expected file to be %s, actual: %s", sitemap.String(), fsys["sitemap.xml"].String())
+	}
+
+	if err := sitemap.WriteToFS(fsys, "sitemap.xml.gz"); err != nil {
+		t.Fatalf("could not write gzipped sitemap to fs: %v", err)
+	}
+	zr, err := gzip.NewReader(fsys["sitemap.xml.gz"])
+	if err != nil {
+		t.Fatalf("could not open gzipped sitemap: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("could not read gzipped sitemap: %v", err)
+	}
+	if string(b) != sitemap.String() {
+		t.Errorf("Expected gzipped file to be %s, actual: %s", sitemap.String(), b)
+	}
+
+	index := NewSitemapIndex()
+	index.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	if err := index.WriteToFS(fsys, "index.xml"); err != nil {
+		t.Fatalf("could not write sitemap index to fs: %v", err)
+	}
+	if fsys["index.xml"].String() != index.String() {
+		t.Errorf("Expected file to be %s, actual: %s", index.String(), fsys["index.xml"].String())
+	}
+
+	for _, name := range []string{"sitemap.txt", "sitemap.gz", "sitemap.txt.gz", "sitemap.xml.zip"} {
+		if err := sitemap.WriteToFS(fsys, name); err == nil {
+			t.Errorf("Expected writing %s to fail", name)
+		}
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemaps := map[string]*Sitemap{}
+	for i := 0; i < 20; i++ {
+		sitemap := New()
+		sitemap.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)})
+		sitemaps[filepath.Join(dir, fmt.Sprintf("sitemap-%d.xml", i))] = sitemap
+	}
+
+	if err := WriteAll(sitemaps); err != nil {
+		t.Fatalf("could not write sitemaps: %v", err)
+	}
+	for path, sitemap := range sitemaps {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("could not read %s: %v", path, err)
+		}
+		if string(b) != sitemap.String() {
+			t.Errorf("Expected %s to be %s, actual: %s", path, sitemap.String(), b)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing", "sitemap.xml")
+	sitemaps[missing] = sitemaps[filepath.Join(dir, "sitemap-0.xml")]
+	sitemaps[filepath.Join(dir, "empty.xml")] = New()
+	err := WriteAll(sitemaps)
+	if err == nil || !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), "empty.xml") {
+		t.Errorf("Expected error to name the files that failed, actual: %v", err)
+	}
+}
+
+// failingWriterTo writes part of a sitemap and then fails
+type failingWriterTo struct{}
+
+func (failingWriterTo) WriteTo(w io.Writer) (int64, error) {
+	n, _ := io.WriteString(w, sitemapHeader)
+	return int64(n), errors.New("disk full")
+}
+
+func TestToFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sitemap.xml")
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	if err := sitemap.ToFile(path); err != nil {
+		t.Fatalf("could not write sitemap: %v", err)
+	}
+
+	if err := writeFile(context.Background(), path, failingWriterTo{}, gzip.DefaultCompression); err == nil {
+		t.Errorf("Expected failing write to return an error")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read sitemap: %v", err)
+	}
+	if string(b) != sitemap.String() {
+		t.Errorf("Expected failed write to keep the previous file %s, actual: %s", sitemap, b)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be removed, actual: %v", entries)
+	}
+}
+
+func TestToFileMode(t *testing.T) {
+	dir := t.TempDir()
+
+	probe, err := os.Create(filepath.Join(dir, "probe"))
+	if err != nil {
+		t.Fatalf("could not create file: %v", err)
+	}
+	probe.Close()
+	info, err := os.Stat(probe.Name())
+	if err != nil {
+		t.Fatalf("could not stat file: %v", err)
+	}
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	path := filepath.Join(dir, "sitemap.xml")
+	if err := sitemap.ToFile(path); err != nil {
+		t.Fatalf("could not write sitemap: %v", err)
+	}
+	written, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat sitemap: %v", err)
+	}
+	if written.Mode().Perm() != info.Mode().Perm() {
+		t.Errorf("Expected new file to have mode %v like os.Create gives it, actual: %v", info.Mode().Perm(), written.Mode().Perm())
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatalf("could not change mode: %v", err)
+	}
+	if err := sitemap.ToFile(path); err != nil {
+		t.Fatalf("could not write sitemap: %v", err)
+	}
+	if written, err = os.Stat(path); err != nil {
+		t.Fatalf("could not stat sitemap: %v", err)
+	}
+	if written.Mode().Perm() != 0600 {
+		t.Errorf("Expected replaced file to keep mode %v, actual: %v", fs.FileMode(0600), written.Mode().Perm())
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	sitemaps := map[string]*Sitemap{
+		"sitemap-2.xml.gz": sitemap,
+		"sitemap-1.xml":    sitemap,
+	}
+	index, err := NewIndex("http://www.google.com/", sitemaps)
+	if err != nil {
+		t.Fatalf("could not create sitemap index: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := WriteZip(&b, index, sitemaps); err != nil {
+		t.Fatalf("could not write zip: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatalf("could not read zip: %v", err)
+	}
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("could not open %s: %v", file.Name, err)
+		}
+		if file.Name == zipIndexName {
+			if _, err := ParseIndex(r); err != nil {
+				t.Errorf("Expected %s to be a sitemap index, actual: %v", file.Name, err)
+			}
+		} else if _, err := Parse(r); err != nil {
+			t.Errorf("Expected %s to be a sitemap, actual: %v", file.Name, err)
+		}
+		r.Close()
+	}
+
+	expected := []string{zipIndexName, "sitemap-1.xml", "sitemap-2.xml.gz"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files to be %v, actual: %v", expected, names)
+	}
+}
@@ -0,0 +1,95 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler returns a handler that serves the sitemap as XML. The response is
+// gzipped for clients that accept it, and the Last-Modified header is the
+// newest LastMod of the items, so that conditional requests with
+// If-Modified-Since get a 304 Not Modified when nothing has changed.
+func (s *Sitemap) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, s, s.LatestMod())
+	})
+}
+
+// Handler returns a handler that serves the sitemap index as XML, like
+// Sitemap.Handler does for a sitemap.
+func (s *SitemapIndex) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, s, s.latestMod())
+	})
+}
+
+// latestMod returns the newest LastMod of the items
+func (s *SitemapIndex) latestMod() time.Time {
+	var latest time.Time
+	for _, item := range s.items {
+		if item.LastMod.After(latest) {
+			latest = item.LastMod
+		}
+	}
+
+	return latest
+}
+
+// serve writes the output of src as the response to r
+func serve(w http.ResponseWriter, r *http.Request, src io.WriterTo, lastMod time.Time) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if !lastMod.IsZero() {
+		w.Header().Set("Last-Modified", lastMod.UTC().Format(http.TimeFormat))
+
+		// The header has a precision of seconds
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err == nil && !lastMod.Truncate(time.Second).After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if !acceptsGzip(r) {
+		src.WriteTo(w)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	zip := gzip.NewWriter(w)
+	src.WriteTo(zip)
+	zip.Close()
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of r allows a gzipped
+// response
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if strings.TrimSpace(name) != "gzip" {
+				continue
+			}
+
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				weight, err := strconv.ParseFloat(q, 64)
+				return err == nil && weight > 0
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
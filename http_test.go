@@ -0,0 +1,76 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: lastMod.Add(-time.Hour)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/about", LastMod: lastMod})
+	handler := sitemap.Handler()
+
+	// Plain
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, actual: %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+		t.Errorf("Expected content type to be application/xml, actual: %s", ct)
+	}
+	if lm := rec.Header().Get("Last-Modified"); lm != "Mon, 31 Mar 2014 14:00:00 GMT" {
+		t.Errorf("Expected last modified to be %s, actual: %s", "Mon, 31 Mar 2014 14:00:00 GMT", lm)
+	}
+	if rec.Body.String() != sitemap.String() {
+		t.Errorf("Expected body to be %s, actual: %s", sitemap.String(), rec.Body.String())
+	}
+
+	// Gzipped
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+	req.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected response to be gzipped")
+	}
+	zip, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("could not read gzipped response: %v", err)
+	}
+	body, _ := io.ReadAll(zip)
+	if string(body) != sitemap.String() {
+		t.Errorf("Expected gzipped body to be %s, actual: %s", sitemap.String(), body)
+	}
+
+	// Not modified
+	for since, code := range map[string]int{
+		"Mon, 31 Mar 2014 14:00:00 GMT": http.StatusNotModified,
+		"Mon, 31 Mar 2014 15:00:00 GMT": http.StatusNotModified,
+		"Mon, 31 Mar 2014 13:59:59 GMT": http.StatusOK,
+	} {
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+		req.Header.Set("If-Modified-Since", since)
+		handler.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Errorf("Expected status %d for If-Modified-Since %s, actual: %d", code, since, rec.Code)
+		}
+	}
+
+	// Index
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml", LastMod: lastMod})
+	rec = httptest.NewRecorder()
+	sitemapIndex.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sitemap-index.xml", nil))
+	if rec.Body.String() != sitemapIndex.String() {
+		t.Errorf("Expected body to be %s, actual: %s", sitemapIndex.String(), rec.Body.String())
+	}
+}
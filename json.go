@@ -0,0 +1,74 @@
+package sitemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonURL is the JSON representation of a SitemapItem
+type jsonURL struct {
+	Loc        string   `json:"loc"`
+	LastMod    string   `json:"lastmod,omitempty"`
+	ChangeFreq string   `json:"changefreq,omitempty"`
+	Priority   *float32 `json:"priority,omitempty"`
+}
+
+// MarshalJSON encodes the item as a JSON object with the fields loc, lastmod,
+// changefreq and priority. The extensions are left out.
+func (i SitemapItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonURL{
+		Loc:        i.Loc,
+		LastMod:    formatTime(i.LastMod, time.RFC3339),
+		ChangeFreq: i.ChangeFreq,
+		Priority:   i.Priority,
+	})
+}
+
+// UnmarshalJSON decodes the item from a JSON object like MarshalJSON returns
+func (i *SitemapItem) UnmarshalJSON(b []byte) error {
+	var u jsonURL
+	if err := json.Unmarshal(b, &u); err != nil {
+		return err
+	}
+
+	*i = SitemapItem{
+		Loc:        u.Loc,
+		ChangeFreq: u.ChangeFreq,
+		Priority:   u.Priority,
+	}
+	if u.LastMod != "" {
+		t, err := time.Parse(time.RFC3339, u.LastMod)
+		if err != nil {
+			return fmt.Errorf("invalid lastmod of %s: %v", u.Loc, err)
+		}
+		i.LastMod = t
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the sitemap as a JSON array of its items
+func (s *Sitemap) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.items == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(s.items)
+}
+
+// UnmarshalJSON replaces the items of the sitemap with those of a JSON array
+// like MarshalJSON returns. The items are checked like Add does.
+func (s *Sitemap) UnmarshalJSON(b []byte) error {
+	var items []SitemapItem
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+
+	s.Reset()
+
+	return s.AddAll(items)
+}
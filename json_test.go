@@ -0,0 +1,43 @@
+package sitemap
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	lastMod := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: lastMod, ChangeFreq: ChangeFreqDaily, Priority: Priority(0.5)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/about"})
+
+	b, err := json.Marshal(sitemap)
+	if err != nil {
+		t.Fatalf("could not marshal sitemap: %v", err)
+	}
+	expected := `[{"loc":"http://www.google.com","lastmod":"2024-03-01T12:00:00Z","changefreq":"daily","priority":0.5},{"loc":"http://www.google.com/about"}]`
+	if string(b) != expected {
+		t.Errorf("Expected JSON to be %s, actual: %s", expected, b)
+	}
+
+	parsed := New()
+	if err := json.Unmarshal(b, parsed); err != nil {
+		t.Fatalf("could not unmarshal sitemap: %v", err)
+	}
+	if parsed.String() != sitemap.String() {
+		t.Errorf("Expected unmarshaled sitemap to be %s, actual: %s", sitemap.String(), parsed.String())
+	}
+
+	if b, _ := json.Marshal(New()); string(b) != "[]" {
+		t.Errorf("Expected JSON of empty sitemap to be %s, actual: %s", "[]", b)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"loc":"/relative"}]`), New()); err == nil {
+		t.Errorf("Expected unmarshaling an invalid item to fail")
+	}
+	if err := json.Unmarshal([]byte(`[{"loc":"http://www.google.com","lastmod":"yesterday"}]`), New()); err == nil {
+		t.Errorf("Expected unmarshaling an invalid lastmod to fail")
+	}
+}
@@ -0,0 +1,69 @@
+package sitemap
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// defaultPorts are the ports that are left out of normalized URLs
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize returns loc in a canonical form, so that URLs that point to the
+// same page are spelled the same: the scheme and host are lowercased, the
+// default port of the scheme is removed, an empty path becomes / and
+// characters that are not allowed in URLs are percent-encoded.
+func Normalize(loc string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(loc))
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrInvalidLoc, loc, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port != "" && defaultPorts[u.Scheme] == port {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = escapeIllegal(u.RawQuery)
+
+	return u.String(), nil
+}
+
+// StripTrailingSlash is like Normalize but also removes the trailing slash
+// of the path, unless the path is just /
+func StripTrailingSlash(loc string) (string, error) {
+	normalized, err := Normalize(loc)
+	if err != nil {
+		return "", err
+	}
+
+	u, _ := url.Parse(normalized)
+	if u.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+
+	return u.String(), nil
+}
+
+// escapeIllegal percent-encodes the characters of s that are not allowed
+// in a URL, leaving existing escapes and reserved characters as they are
+func escapeIllegal(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"<>\^`+"`{|}", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package sitemap
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"http://www.google.com":                 "http://www.google.com/",
+		"HTTP://WWW.Google.COM/About":           "http://www.google.com/About",
+		"http://www.google.com:80/a":            "http://www.google.com/a",
+		"https://www.google.com:443/a":          "https://www.google.com/a",
+		"http://www.google.com:8080/a":          "http://www.google.com:8080/a",
+		"http://www.google.com/a b?q=x y":       "http://www.google.com/a%20b?q=x%20y",
+		"http://www.google.com/caf%C3%A9?q=%20": "http://www.google.com/caf%C3%A9?q=%20",
+		"http://www.google.com/a/":              "http://www.google.com/a/",
+	}
+	for loc, expected := range tests {
+		actual, err := Normalize(loc)
+		if err != nil {
+			t.Errorf("could not normalize %s: %v", loc, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("Expected %s to be normalized to %s, actual: %s", loc, expected, actual)
+		}
+	}
+
+	if _, err := Normalize("http://www.google.com/%zz"); err == nil {
+		t.Errorf("Expected normalizing an invalid URL to fail")
+	}
+}
+
+func TestStripTrailingSlash(t *testing.T) {
+	tests := map[string]string{
+		"http://www.google.com":        "http://www.google.com/",
+		"http://www.google.com/":       "http://www.google.com/",
+		"http://www.google.com/a/":     "http://www.google.com/a",
+		"http://www.google.com/a/?q=1": "http://www.google.com/a?q=1",
+		"http://www.google.com/a%2Fb/": "http://www.google.com/a%2Fb",
+	}
+	for loc, expected := range tests {
+		actual, err := StripTrailingSlash(loc)
+		if err != nil {
+			t.Errorf("could not normalize %s: %v", loc, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("Expected %s to be normalized to %s, actual: %s", loc, expected, actual)
+		}
+	}
+}
+
+func TestSetNormalizeFunc(t *testing.T) {
+	sitemap := New()
+	sitemap.SetNormalizeFunc(StripTrailingSlash)
+
+	sitemap.AddUnique(SitemapItem{Loc: "http://WWW.GOOGLE.COM:80/a/"})
+	if added, _ := sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/a"}); added {
+		t.Errorf("Expected normalized duplicate not to be added")
+	}
+	if loc := sitemap.items[0].Loc; loc != "http://www.google.com/a" {
+		t.Errorf("Expected loc to be normalized to %s, actual: %s", "http://www.google.com/a", loc)
+	}
+}
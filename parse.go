@@ -0,0 +1,232 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Parse reads a sitemap from r, which may be gzipped. The items are added
+// with Sitemap.Add, so a sitemap that does not follow the protocol results
+// in an error. A lastmod is parsed with ParseLastMod and left out if it
+// matches none of LastModLayouts. The elements of the supported extensions
+// are parsed into the fields of the items, other elements are left out and
+// Extra stays empty. The sitemap formats LastMod with the layout of the
+// first lastmod, so that a sitemap with only dates is written again with
+// only dates.
+func Parse(r io.Reader) (*Sitemap, error) {
+	return parseSitemap(r, false, (*Sitemap).Add)
+}
+
+// ParseStrict is like Parse but fails on a lastmod that matches none of
+// LastModLayouts
+func ParseStrict(r io.Reader) (*Sitemap, error) {
+	return parseSitemap(r, true, (*Sitemap).Add)
+}
+
+// parseSitemap reads a sitemap from r into a new sitemap, adding the items
+// with add, and sets its lastmod layout to the one of the first lastmod
+func parseSitemap(r io.Reader, strict bool, add func(*Sitemap, SitemapItem) error) (*Sitemap, error) {
+	s := New()
+	layout := func(layout string) {
+		// RFC3339Nano also matches the default RFC3339 layout
+		if layout != time.RFC3339Nano {
+			s.SetLastModFormat(layout)
+		}
+	}
+	err := parseStream(r, strict, layout, func(item SitemapItem) error {
+		return add(s, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ParseStream reads a sitemap from r, which may be gzipped, and calls fn for
+// every item as soon as it is read, so that a large sitemap never has to be
+// held in memory. The items are not checked and a lastmod is parsed like
+// Parse does. Parsing stops with the error of fn if it returns one.
+func ParseStream(r io.Reader, fn func(SitemapItem) error) error {
+	return parseStream(r, false, nil, fn)
+}
+
+// parseStream is ParseStream, failing on an invalid lastmod if strict is set.
+// If layout is not nil, it is called with the layout of the first lastmod
+// that matches one of LastModLayouts before fn is called for its item.
+func parseStream(r io.Reader, strict bool, layout func(string), fn func(SitemapItem) error) error {
+	r, err := decompress(r)
+	if err != nil {
+		return err
+	}
+
+	d := xml.NewDecoder(r)
+	root := true
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			if root {
+				return errors.New("expected element type <urlset> but have none")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if root {
+			if start.Name.Local != "urlset" {
+				return fmt.Errorf("expected element type <urlset> but have <%s>", start.Name.Local)
+			}
+			root = false
+			continue
+		}
+
+		if start.Name.Local != "url" {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var u xmlParsedURL
+		if err := d.DecodeElement(&u, &start); err != nil {
+			return err
+		}
+
+		item, err := u.item(strict)
+		if err != nil {
+			return err
+		}
+		if layout != nil && !item.LastMod.IsZero() {
+			if _, l, err := ParseLastMod(u.LastMod); err == nil {
+				layout(l)
+				layout = nil
+			}
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// FromURLList reads a sitemap from a list of URLs in r, one per line. Blank
+// lines and lines starting with # are skipped. The URLs are added with
+// Sitemap.Add and errors name the line of the URL.
+func FromURLList(r io.Reader) (*Sitemap, error) {
+	s := New()
+
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, long, err := readLine(reader)
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if long {
+			return nil, fmt.Errorf("line %d: %w, it is longer than the maximum of %d bytes", line, ErrInvalidLoc, maxURLListLine)
+		}
+
+		loc := strings.TrimSpace(string(text))
+		if loc == "" || strings.HasPrefix(loc, "#") {
+			continue
+		}
+
+		if err := s.AddURL(loc); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// maxURLListLine is the longest line FromURLList reads, a longer line can
+// not hold a valid loc and is not read into memory
+const maxURLListLine = 1 << 20
+
+// readLine returns the next line of r without its line ending, or io.EOF
+// once there are no more lines. A line longer than maxURLListLine is
+// reported with long set and the rest of it is skipped.
+func readLine(r *bufio.Reader) (line []byte, long bool, err error) {
+	for {
+		fragment, more, err := r.ReadLine()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(line)+len(fragment) > maxURLListLine {
+			long = true
+		} else if !long {
+			line = append(line, fragment...)
+		}
+
+		if !more {
+			return line, long, nil
+		}
+	}
+}
+
+// ParseIndex reads a sitemap index from r, which may be gzipped. A lastmod
+// is parsed like Parse does.
+func ParseIndex(r io.Reader) (*SitemapIndex, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var index xmlSitemapIndex
+	if err := xml.NewDecoder(r).Decode(&index); err != nil {
+		return nil, err
+	}
+
+	s := &SitemapIndex{
+		items: make([]SitemapIndexItem, 0, len(index.Sitemaps)),
+	}
+	for _, sitemap := range index.Sitemaps {
+		item, err := sitemap.item(false)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Add(item)
+	}
+
+	return s, nil
+}
+
+// maxGzipLayers is the number of times decompress decompresses a sitemap,
+// enough for a gzipped sitemap served with gzip content encoding
+const maxGzipLayers = 2
+
+// decompress returns a reader of the decompressed content of r for as long
+// as it starts with the gzip magic bytes, so that a gzipped sitemap served
+// with gzip content encoding is decompressed twice. A sitemap that is not
+// gzipped is read as is. Content that is still gzipped after maxGzipLayers
+// is rejected, so that nested gzip can not keep it decompressing forever.
+func decompress(r io.Reader) (io.Reader, error) {
+	for layers := 0; ; layers++ {
+		br := bufio.NewReader(r)
+		magic, err := br.Peek(2)
+		if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+			return br, nil
+		}
+		if layers == maxGzipLayers {
+			return nil, fmt.Errorf("sitemap is gzipped more than %d times", maxGzipLayers)
+		}
+
+		if r, err = gzip.NewReader(br); err != nil {
+			return nil, err
+		}
+	}
+}
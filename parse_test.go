@@ -0,0 +1,316 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: lastMod, ChangeFreq: ChangeFreqHourly, Priority: Priority(0.5)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/search?a=1&b=2"})
+
+	var gzipped bytes.Buffer
+	zip := gzip.NewWriter(&gzipped)
+	sitemap.WriteTo(zip)
+	zip.Close()
+
+	var twice bytes.Buffer
+	zip = gzip.NewWriter(&twice)
+	zip.Write(gzipped.Bytes())
+	zip.Close()
+
+	inputs := map[string][]byte{
+		"plain":         []byte(sitemap.String()),
+		"gzipped":       gzipped.Bytes(),
+		"gzipped twice": twice.Bytes(),
+	}
+
+	for name, input := range inputs {
+		parsed, err := Parse(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("could not parse %s sitemap: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(parsed.items, sitemap.items) {
+			t.Errorf("Expected %s sitemap items to be parsed as %+v, actual: %+v", name, sitemap.items, parsed.items)
+		}
+	}
+
+	_, err := Parse(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?><sitemapindex></sitemapindex>`))
+	if err == nil {
+		t.Errorf("Expected parsing a sitemap index as a sitemap to fail")
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	publicationDate := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := SitemapItem{
+		Loc:        "http://www.google.com",
+		Images:     []Image{{Loc: "http://www.google.com/a.png", Caption: "A & B", Title: "A"}},
+		Videos:     []Video{{ThumbnailLoc: "http://www.google.com/a.jpg", Title: "A", Description: "B", Duration: 90 * time.Second, PublicationDate: publicationDate}},
+		News:       &NewsInfo{PublicationName: "Google", PublicationLanguage: "en", PublicationDate: publicationDate, Title: "A"},
+		Alternates: []Alternate{{Hreflang: "en", Href: "http://www.google.com"}, {Hreflang: "de", Href: "http://www.google.de"}},
+		Mobile:     true,
+		Geo:        &GeoInfo{Format: "kml"},
+	}
+
+	sitemap := New()
+	if err := sitemap.Add(item); err != nil {
+		t.Fatalf("could not add item: %v", err)
+	}
+
+	parsed, err := Parse(strings.NewReader(sitemap.String()))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.items, []SitemapItem{item}) {
+		t.Errorf("Expected parsed items to be %v, actual: %v", []SitemapItem{item}, parsed.items)
+	}
+
+	prefixed := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:img="http://www.google.com/schemas/sitemap-image/1.1" xmlns:other="http://www.google.com/other">
+	<url>
+		<loc>http://www.google.com</loc>
+		<img:image><img:loc>http://www.google.com/a.png</img:loc></img:image>
+		<other:image><other:loc>http://www.google.com/b.png</other:loc></other:image>
+	</url>
+</urlset>`
+	parsed, err = Parse(strings.NewReader(prefixed))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	expected := []Image{{Loc: "http://www.google.com/a.png"}}
+	if !reflect.DeepEqual(parsed.items[0].Images, expected) {
+		t.Errorf("Expected images to be matched by namespace %v, actual: %v", expected, parsed.items[0].Images)
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	sitemapIndex := SitemapIndex{
+		items: []SitemapIndexItem{
+			{"http://www.google.com/sitemap-1.xml.gz", lastMod},
+			{"http://www.google.com/sitemap-2.xml.gz", lastMod},
+		},
+	}
+
+	var gzipped bytes.Buffer
+	zip := gzip.NewWriter(&gzipped)
+	sitemapIndex.WriteTo(zip)
+	zip.Close()
+
+	inputs := map[string][]byte{
+		"plain":   []byte(sitemapIndex.String()),
+		"gzipped": gzipped.Bytes(),
+	}
+
+	for name, input := range inputs {
+		parsed, err := ParseIndex(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("could not parse %s sitemap index: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(parsed.items, sitemapIndex.items) {
+			t.Errorf("Expected %s sitemap index items to be parsed as %+v, actual: %+v", name, sitemapIndex.items, parsed.items)
+		}
+	}
+
+	_, err := ParseIndex(strings.NewReader(sitemapResult))
+	if err == nil {
+		t.Errorf("Expected parsing a sitemap as a sitemap index to fail")
+	}
+}
+
+func TestFromURLList(t *testing.T) {
+	list := `# pages
+http://www.google.com/
+
+  http://www.google.com/about  
+# http://www.google.com/hidden
+`
+	sitemap, err := FromURLList(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("could not read url list: %v", err)
+	}
+
+	expected := New()
+	expected.AddURL("http://www.google.com/")
+	expected.AddURL("http://www.google.com/about")
+	if sitemap.String() != expected.String() {
+		t.Errorf("Expected sitemap to be %s, actual: %s", expected.String(), sitemap.String())
+	}
+
+	long := "http://www.google.com/" + strings.Repeat("a", MaxURLLength)
+	_, err = FromURLList(strings.NewReader("http://www.google.com/\n\n" + long + "\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("Expected error for the long URL on line 3, actual: %v", err)
+	}
+	if !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected error to be %v, actual: %v", ErrInvalidLoc, err)
+	}
+
+	huge := strings.Repeat("a", 2*maxURLListLine)
+	_, err = FromURLList(strings.NewReader("http://www.google.com/\n" + huge + "\nhttp://www.google.com/about\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") || !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected %v for the huge line 2, actual: %v", ErrInvalidLoc, err)
+	}
+
+	broken := errors.New("broken")
+	sitemap, err = FromURLList(io.MultiReader(strings.NewReader("http://www.google.com/\n"), iotest.ErrReader(broken)))
+	if !errors.Is(err, broken) || sitemap != nil {
+		t.Errorf("Expected read error and no sitemap, actual: %v, %v", sitemap, err)
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a"})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b", Images: []Image{{Loc: "http://www.google.com/b.png"}}})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/c"})
+
+	var locs []string
+	err := ParseStream(strings.NewReader(sitemap.String()), func(item SitemapItem) error {
+		locs = append(locs, item.Loc)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	expected := []string{"http://www.google.com/a", "http://www.google.com/b", "http://www.google.com/c"}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Expected parsed locs to be %v, actual: %v", expected, locs)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = ParseStream(strings.NewReader(sitemap.String()), func(item SitemapItem) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected parsing to stop with the error of the callback after 1 call, actual: %v after %d calls", err, calls)
+	}
+
+	if err := ParseStream(strings.NewReader(""), func(SitemapItem) error { return nil }); err == nil {
+		t.Errorf("Expected parsing an empty input to fail")
+	}
+}
+
+func TestParseLastMod(t *testing.T) {
+	tests := map[string]struct {
+		expected time.Time
+		layout   string
+	}{
+		"2024-03-01T12:30:15+01:00": {time.Date(2024, 3, 1, 12, 30, 15, 0, time.FixedZone("", 3600)), time.RFC3339Nano},
+		"2024-03-01T12:30:15.5Z":    {time.Date(2024, 3, 1, 12, 30, 15, 500000000, time.UTC), time.RFC3339Nano},
+		"2024-03-01T12:30Z":         {time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), "2006-01-02T15:04Z07:00"},
+		"2024-03-01":                {time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.DateOnly},
+		"2024-03":                   {time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "2006-01"},
+		" 2024 ":                    {time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2006"},
+	}
+
+	for value, test := range tests {
+		actual, layout, err := ParseLastMod(value)
+		if err != nil {
+			t.Errorf("could not parse %q: %v", value, err)
+			continue
+		}
+		if !actual.Equal(test.expected) || layout != test.layout {
+			t.Errorf("Expected %q to be %s with layout %s, actual: %s with layout %s", value, test.expected, test.layout, actual, layout)
+		}
+	}
+
+	if _, _, err := ParseLastMod("yesterday"); err == nil {
+		t.Errorf("Expected parsing an invalid lastmod to fail")
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://www.google.com/a</loc><lastmod>2024-03-01</lastmod></url>
+	<url><loc>http://www.google.com/b</loc><lastmod>yesterday</lastmod></url>
+</urlset>`
+
+	sitemap, err := Parse(strings.NewReader(xml))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	if !sitemap.items[0].LastMod.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected date-only lastmod to be parsed, actual: %s", sitemap.items[0].LastMod)
+	}
+	if !sitemap.items[1].LastMod.IsZero() {
+		t.Errorf("Expected invalid lastmod to be left out, actual: %s", sitemap.items[1].LastMod)
+	}
+
+	if _, err := ParseStrict(strings.NewReader(xml)); err == nil || !strings.Contains(err.Error(), "yesterday") {
+		t.Errorf("Expected strict parsing to report the invalid lastmod, actual: %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{
+		Loc:        "http://www.google.com",
+		LastMod:    time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.FixedZone("", 3600)),
+		ChangeFreq: ChangeFreqDaily,
+		Priority:   Priority(0.8),
+	})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/about", Priority: Priority(0.33)})
+	sitemap.Add(SitemapItem{
+		Loc:        "http://www.google.com/news",
+		Images:     []Image{{Loc: "http://www.google.com/news.png", Caption: "News"}},
+		News:       &NewsInfo{PublicationName: "Google", PublicationLanguage: "en", PublicationDate: time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.UTC), Title: "News"},
+		Alternates: []Alternate{{Hreflang: "de", Href: "http://www.google.de/news"}},
+	})
+
+	parsed, err := Parse(strings.NewReader(sitemap.String()))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	if !parsed.Equal(sitemap) {
+		t.Errorf("Expected parsed sitemap to equal %s, actual: %s", sitemap, parsed)
+	}
+
+	parsed.Touch("http://www.google.com/about", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+	if parsed.Equal(sitemap) {
+		t.Errorf("Expected sitemaps with different items not to be equal")
+	}
+
+	sitemap.SetLastModFormat(time.DateOnly)
+	parsed, err = Parse(strings.NewReader(sitemap.String()))
+	if err != nil {
+		t.Fatalf("could not parse sitemap: %v", err)
+	}
+	if !parsed.Equal(sitemap) || !sitemap.Equal(parsed) {
+		t.Errorf("Expected parsed sitemap with lastmod format %q to equal %s, actual: %s", time.DateOnly, sitemap, parsed)
+	}
+	if parsed.String() != sitemap.String() {
+		t.Errorf("Expected parsed sitemap to be written as %s, actual: %s", sitemap, parsed)
+	}
+}
+
+func TestParseNestedGzip(t *testing.T) {
+	b := []byte(New().String())
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		zip := gzip.NewWriter(&buf)
+		zip.Write(b)
+		zip.Close()
+		b = buf.Bytes()
+	}
+
+	if _, err := Parse(bytes.NewReader(b)); err == nil || !strings.Contains(err.Error(), "gzipped more than") {
+		t.Errorf("Expected a sitemap gzipped three times to be rejected, actual: %v", err)
+	}
+}
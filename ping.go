@@ -0,0 +1,101 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Ping endpoints of search engines, to which the URL of the sitemap is
+// appended
+const (
+	GooglePing = "https://www.google.com/ping?sitemap="
+	BingPing   = "https://www.bing.com/ping?sitemap="
+)
+
+// HTTPClient is the client Ping uses, and the other functions that send
+// requests to other hosts when they are given no client. It can be replaced
+// to configure timeouts and transports, or in tests.
+var HTTPClient = &http.Client{}
+
+// Ping notifies search engines that the sitemap at sitemapURL has changed
+// with HTTPClient. The engines are ping endpoints like GooglePing, which is
+// used together with BingPing when none are given. All engines are pinged,
+// the returned error combines the errors of those that failed.
+//
+// Both search engines have deprecated their ping endpoints: Google no
+// longer acts on pings and Bing recommends IndexNow instead. Submitting the
+// sitemap in robots.txt or in the webmaster tools of the search engines is
+// the lasting way to announce it.
+func Ping(ctx context.Context, sitemapURL string, engines ...string) error {
+	if len(engines) == 0 {
+		engines = []string{GooglePing, BingPing}
+	}
+
+	var errs []error
+	for _, engine := range engines {
+		if err := ping(ctx, engine+url.QueryEscape(sitemapURL)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ping sends a GET request to u and checks that it succeeded
+func ping(ctx context.Context, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("ping %s failed: %s", u, resp.Status)
+	}
+
+	return nil
+}
+
+// NotifyHub notifies the WebSub hub at hubURL that the sitemap published at
+// topicURL has changed, so that the hub can tell its subscribers. If client
+// is nil HTTPClient is used.
+// See https://www.w3.org/TR/websub/#publishing
+func (s *Sitemap) NotifyHub(ctx context.Context, hubURL, topicURL string, client *http.Client) error {
+	if client == nil {
+		client = HTTPClient
+	}
+
+	form := url.Values{
+		"hub.mode": {"publish"},
+		"hub.url":  {topicURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hubURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notifying hub %s failed: %s", hubURL, resp.Status)
+	}
+
+	return nil
+}
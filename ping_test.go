@@ -0,0 +1,74 @@
+package sitemap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPing(t *testing.T) {
+	var pinged []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pinged = append(pinged, r.URL.Path+" "+r.URL.Query().Get("sitemap"))
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL := "http://www.google.com/sitemap.xml?lang=en&page=1"
+
+	err := Ping(context.Background(), sitemapURL, server.URL+"/a?sitemap=", server.URL+"/b?sitemap=")
+	if err != nil {
+		t.Errorf("could not ping: %v", err)
+	}
+	expected := []string{"/a " + sitemapURL, "/b " + sitemapURL}
+	if strings.Join(pinged, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected pings to be %v, actual: %v", expected, pinged)
+	}
+
+	pinged = nil
+	err = Ping(context.Background(), sitemapURL, server.URL+"/broken?sitemap=", server.URL+"/a?sitemap=")
+	if err == nil || !strings.Contains(err.Error(), "/broken") {
+		t.Errorf("Expected error for the broken endpoint, actual: %v", err)
+	}
+	if len(pinged) != 2 {
+		t.Errorf("Expected all endpoints to be pinged despite the error, actual: %v", pinged)
+	}
+}
+
+func TestNotifyHub(t *testing.T) {
+	var mode, topic, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected method to be %s, actual: %s", http.MethodPost, r.Method)
+		}
+		contentType = r.Header.Get("Content-Type")
+		mode, topic = r.PostFormValue("hub.mode"), r.PostFormValue("hub.url")
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sitemap := New()
+	topicURL := "http://www.google.com/sitemap.xml"
+
+	if err := sitemap.NotifyHub(context.Background(), server.URL+"/hub", topicURL, server.Client()); err != nil {
+		t.Errorf("could not notify hub: %v", err)
+	}
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected content type to be %s, actual: %s", "application/x-www-form-urlencoded", contentType)
+	}
+	if mode != "publish" || topic != topicURL {
+		t.Errorf("Expected hub.mode=publish and hub.url=%s, actual: hub.mode=%s and hub.url=%s", topicURL, mode, topic)
+	}
+
+	if err := sitemap.NotifyHub(context.Background(), server.URL+"/broken", topicURL, nil); err == nil {
+		t.Errorf("Expected notifying a failing hub to fail")
+	}
+}
@@ -0,0 +1,47 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Report describes what ToFile would write for a sitemap, see Preview
+type Report struct {
+	// Items is the number of items
+	Items int
+
+	// Size is the size of the sitemap in bytes
+	Size int64
+
+	// GzipSize is the size of the gzipped sitemap in bytes, with the default
+	// compression level
+	GzipSize int64
+
+	// Warnings are the problems Validate found, one per problem
+	Warnings []error
+}
+
+// Preview renders the sitemap without writing it anywhere and reports its
+// size and the problems Validate finds. The returned error is that of
+// Validate, so that a sitemap that should not be published fails.
+func (s *Sitemap) Preview() (Report, error) {
+	err := s.Validate()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := Report{
+		Items: len(s.items),
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		report.Warnings = joined.Unwrap()
+	}
+
+	compressed := &countWriter{w: io.Discard}
+	zip := gzip.NewWriter(compressed)
+	report.Size, _ = s.writeTo(zip)
+	zip.Close()
+	report.GzipSize = compressed.n
+
+	return report, err
+}
@@ -0,0 +1,44 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a"})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b"})
+
+	report, err := sitemap.Preview()
+	if err != nil {
+		t.Fatalf("Expected sitemap to be valid, got error: %v", err)
+	}
+
+	var gzipped bytes.Buffer
+	zip := gzip.NewWriter(&gzipped)
+	sitemap.WriteTo(zip)
+	zip.Close()
+
+	expected := Report{
+		Items:    2,
+		Size:     int64(len(sitemap.String())),
+		GzipSize: int64(gzipped.Len()),
+	}
+	if report.Items != expected.Items || report.Size != expected.Size || report.GzipSize != expected.GzipSize || len(report.Warnings) != 0 {
+		t.Errorf("Expected report to be %+v, actual: %+v", expected, report)
+	}
+
+	sitemap.items = append(sitemap.items,
+		SitemapItem{Loc: "/relative"},
+		SitemapItem{Loc: "http://www.google.com/c", Priority: Priority(2)},
+	)
+	report, err = sitemap.Preview()
+	if err == nil {
+		t.Errorf("Expected preview of an invalid sitemap to fail")
+	}
+	if report.Items != 4 || len(report.Warnings) != 2 {
+		t.Errorf("Expected report of %d items with %d warnings, actual: %+v", 4, 2, report)
+	}
+}
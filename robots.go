@@ -0,0 +1,58 @@
+package sitemap
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// WriteRobotsDirectives writes a Sitemap directive for each of urls to w,
+// for inclusion in a robots.txt file. The urls must be absolute, as robots.txt
+// requires.
+func WriteRobotsDirectives(w io.Writer, urls []string) error {
+	for _, u := range urls {
+		if err := validRobotsURL(u); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range urls {
+		if _, err := fmt.Fprintf(w, "Sitemap: %s\n", u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RobotsDirectives returns a Sitemap directive for each sitemap in the index,
+// for inclusion in a robots.txt file. Sitemaps without an absolute Loc are
+// left out.
+func (s *SitemapIndex) RobotsDirectives() string {
+	var b strings.Builder
+	for _, item := range s.items {
+		if validRobotsURL(item.Loc) == nil {
+			fmt.Fprintf(&b, "Sitemap: %s\n", item.Loc)
+		}
+	}
+
+	return b.String()
+}
+
+// validRobotsURL checks that u is an absolute URL that fits on a single line
+func validRobotsURL(u string) error {
+	if strings.ContainsAny(u, "\r\n") {
+		return fmt.Errorf("sitemap URL %q contains a line break", u)
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("sitemap URL %q is not a valid URL: %v", u, err)
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return fmt.Errorf("sitemap URL %q is not an absolute URL", u)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package sitemap
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRobotsDirectives(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteRobotsDirectives(&buf, []string{
+		"http://www.google.com/sitemap-1.xml.gz",
+		"http://www.google.com/sitemap-2.xml.gz",
+	})
+	if err != nil {
+		t.Fatalf("could not write robots directives: %v", err)
+	}
+
+	expected := "Sitemap: http://www.google.com/sitemap-1.xml.gz\nSitemap: http://www.google.com/sitemap-2.xml.gz\n"
+	if buf.String() != expected {
+		t.Errorf("Expected robots directives to be %q, actual: %q", expected, buf.String())
+	}
+
+	for _, u := range []string{"/sitemap.xml", "http://www.google.com/sitemap.xml\nDisallow: /"} {
+		if err := WriteRobotsDirectives(&buf, []string{u}); err == nil {
+			t.Errorf("Expected sitemap URL %q to be rejected", u)
+		}
+	}
+
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap-1.xml.gz"})
+	sitemapIndex.Add(SitemapIndexItem{Loc: "sitemap-3.xml.gz"})
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap-2.xml.gz"})
+	if sitemapIndex.RobotsDirectives() != expected {
+		t.Errorf("Expected robots directives of sitemap index to be %q, actual: %q", expected, sitemapIndex.RobotsDirectives())
+	}
+}
@@ -0,0 +1,146 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// RollingWriter streams items to gzipped sitemap files in a directory,
+// starting a new file whenever the current one is full. Close returns a
+// sitemap index of the written files.
+type RollingWriter struct {
+	dir        string
+	baseName   string
+	pathPrefix string
+
+	file   io.WriteCloser
+	enc    *Encoder
+	name   string
+	files  int
+	index  *SitemapIndex
+	closed bool
+
+	// maxItems is the maximum number of items per file, see SetMaxItems
+	maxItems int
+}
+
+// NewRollingWriter returns a writer that writes the sitemaps baseName-1.xml.gz,
+// baseName-2.xml.gz and so on to dir. The locations in the index are the
+// filenames joined to pathPrefix. No file is created before the first item
+// is added.
+func NewRollingWriter(dir, baseName, pathPrefix string) *RollingWriter {
+	return &RollingWriter{
+		dir:        dir,
+		baseName:   baseName,
+		pathPrefix: pathPrefix,
+		index:      NewSitemapIndex(),
+	}
+}
+
+// SetMaxItems lowers the maximum number of items per file like
+// Sitemap.SetMaxItems does. It applies from the next file on.
+func (w *RollingWriter) SetMaxItems(n int) {
+	w.maxItems = n
+}
+
+// Add writes item to the current file, or to a new one if the current file
+// has reached the maximum number of items or the maximum size. It applies the same
+// checks as Sitemap.Add.
+func (w *RollingWriter) Add(item SitemapItem) error {
+	if w.closed {
+		return errors.New("rolling writer is closed")
+	}
+
+	if w.enc == nil {
+		// Check the item first, so that no file is created for it if it is
+		// invalid
+		if err := item.Validate(); err != nil {
+			return err
+		}
+		if err := w.next(); err != nil {
+			return err
+		}
+	}
+
+	err := w.enc.Encode(item)
+	if (errors.Is(err, ErrMaxItemsExceeded) || errors.Is(err, ErrMaxSizeExceeded)) && w.enc.items > 0 {
+		if err := w.finish(); err != nil {
+			return err
+		}
+		if err := w.next(); err != nil {
+			return err
+		}
+		err = w.enc.Encode(item)
+	}
+
+	return err
+}
+
+// Close finishes the current file and returns a sitemap index of all
+// written files
+func (w *RollingWriter) Close() (*SitemapIndex, error) {
+	if w.closed {
+		return w.index, errors.New("rolling writer is closed")
+	}
+	w.closed = true
+
+	if w.enc != nil {
+		if err := w.finish(); err != nil {
+			return w.index, err
+		}
+	}
+
+	return w.index, nil
+}
+
+// next creates the next file and an encoder writing to it
+func (w *RollingWriter) next() error {
+	w.files++
+	w.name = fmt.Sprintf("%s-%d.xml.gz", w.baseName, w.files)
+
+	var err error
+	w.file, err = osFS{}.Create(filepath.Join(w.dir, w.name))
+	if err != nil {
+		return err
+	}
+
+	w.enc, err = NewGzipEncoder(w.file, gzip.DefaultCompression)
+	if err != nil {
+		closeOrAbort(w.file, err)
+		w.enc = nil
+		return err
+	}
+	w.enc.SetMaxItems(w.maxItems)
+
+	return nil
+}
+
+// finish closes the current file and adds it to the index, or discards it
+// if it has no items
+func (w *RollingWriter) finish() error {
+	if w.enc.items == 0 {
+		closeOrAbort(w.file, ErrEmptySitemap)
+		w.enc, w.file = nil, nil
+		return nil
+	}
+
+	err := closeOrAbort(w.file, w.enc.Close())
+	w.enc, w.file = nil, nil
+	if err != nil {
+		return err
+	}
+
+	loc, err := joinURL(w.pathPrefix, w.name)
+	if err != nil {
+		return err
+	}
+	w.index.Add(SitemapIndexItem{
+		Loc:     loc,
+		LastMod: Now(),
+	})
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package sitemap
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRollingWriter(t *testing.T) {
+	dir := t.TempDir()
+
+	w := NewRollingWriter(dir, "posts", "http://www.google.com/sitemaps/")
+	w.SetMaxItems(2)
+	for i := 0; i < 3; i++ {
+		if err := w.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)}); err != nil {
+			t.Fatalf("could not add item %d: %v", i, err)
+		}
+	}
+	if err := w.Add(SitemapItem{Loc: "/relative"}); err == nil {
+		t.Errorf("Expected adding an invalid item to fail")
+	}
+
+	index, err := w.Close()
+	if err != nil {
+		t.Fatalf("could not close rolling writer: %v", err)
+	}
+	if index.Len() != 2 {
+		t.Fatalf("Expected index to have %d sitemaps, actual: %d", 2, index.Len())
+	}
+
+	for i, item := range index.items {
+		name := fmt.Sprintf("posts-%d.xml.gz", i+1)
+		if expected := "http://www.google.com/sitemaps/" + name; item.Loc != expected {
+			t.Errorf("Expected loc of sitemap %d to be %s, actual: %s", i, expected, item.Loc)
+		}
+
+		f, err := os.Open(path.Join(dir, name))
+		if err != nil {
+			t.Fatalf("could not open %s: %v", name, err)
+		}
+		s, err := Parse(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("could not parse %s: %v", name, err)
+		}
+		if expected := []int{2, 1}[i]; s.Len() != expected {
+			t.Errorf("Expected %s to have %d items, actual: %d", name, expected, s.Len())
+		}
+	}
+
+	if err := w.Add(SitemapItem{Loc: "http://www.google.com"}); err == nil {
+		t.Errorf("Expected adding to a closed rolling writer to fail")
+	}
+
+	empty, err := NewRollingWriter(dir, "empty", "").Close()
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("Expected rolling writer without items to write no sitemaps, actual: %d, %v", empty.Len(), err)
+	}
+
+	invalid := NewRollingWriter(dir, "invalid", "")
+	if err := invalid.Add(SitemapItem{Loc: "relative"}); err == nil {
+		t.Errorf("Expected adding an invalid first item to fail")
+	}
+	index, err = invalid.Close()
+	if err != nil || index.Len() != 0 {
+		t.Errorf("Expected rolling writer with only an invalid item to write no sitemaps, actual: %d, %v", index.Len(), err)
+	}
+	if _, err := os.Stat(path.Join(dir, "invalid-1.xml.gz")); err == nil {
+		t.Errorf("Expected no file for the invalid item")
+	}
+}
@@ -2,12 +2,24 @@ package sitemap
 
 import (
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,14 +27,24 @@ const (
 	// MaxSitemapItems is the maximum number of items for a single sitemap
 	MaxSitemapItems = 50000
 
+	// MaxSitemapIndexItems is the maximum number of sitemaps in a sitemap
+	// index
+	MaxSitemapIndexItems = 50000
+
+	// MaxURLLength is the maximum length of the loc of a sitemap item
+	MaxURLLength = 2048
+
+	// MaxUncompressedBytes is the maximum size of a sitemap or sitemap
+	// index before it is gzipped
+	MaxUncompressedBytes = 52428800
+
 	// SitemapXML is the XML structure for urlset in sitemaps
-	SitemapXML = `<?xml version="1.0" encoding="UTF-8"?>
-<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
-	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
-	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">%s
-</urlset>`
+	SitemapXML = sitemapHeader + "%s" + sitemapFooter
 
 	// SitemapItemXML is the XML format for the URL item in sitemap
+	//
+	// Deprecated: items are encoded with encoding/xml, which leaves out
+	// unset optional elements.
 	SitemapItemXML = `
 	<url>
 		<loc>%s</loc>
@@ -32,12 +54,11 @@ const (
 	</url>`
 
 	// SitemapIndexXML is the XML structure of a sitemap index
-	SitemapIndexXML = `<?xml version="1.0" encoding="UTF-8"?>
-<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">%s
-</sitemapindex>
-`
+	SitemapIndexXML = sitemapIndexHeader + "%s" + sitemapIndexFooter
 
 	// SitemapIndexItemXML is the XML structure of a sitemap index item
+	//
+	// Deprecated: items are encoded with encoding/xml.
 	SitemapIndexItemXML = `
 	<sitemap>
 		<loc>%s</loc>
@@ -45,172 +66,1983 @@ const (
 	</sitemap>`
 )
 
-// Sitemap represent a sitemap
+const (
+	// xmlDeclaration is the XML declaration at the start of the output
+	xmlDeclaration = `<?xml version="1.0" encoding="UTF-8"?>
+`
+
+	// urlsetStart is the start of the urlset tag, before any extension
+	// namespaces are declared
+	urlsetStart = xmlDeclaration + `<urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd"
+	xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"`
+	sitemapHeader = urlsetStart + ">"
+	sitemapFooter = `
+</urlset>`
+
+	sitemapIndexHeader = xmlDeclaration + `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+	sitemapIndexFooter = `
+</sitemapindex>
+`
+
+	// futureLastModTolerance is how far in the future a lastmod may be
+	// before Validate reports it, to allow for clock skew
+	futureLastModTolerance = 5 * time.Minute
+
+	// itemSeparator is written between two items
+	itemSeparator = `
+`
+)
+
+// Valid values for the change frequency of a sitemap item
+const (
+	ChangeFreqAlways  = "always"
+	ChangeFreqHourly  = "hourly"
+	ChangeFreqDaily   = "daily"
+	ChangeFreqWeekly  = "weekly"
+	ChangeFreqMonthly = "monthly"
+	ChangeFreqYearly  = "yearly"
+	ChangeFreqNever   = "never"
+)
+
+// ChangeFreqs lists the change frequencies allowed by the sitemap protocol
+var ChangeFreqs = []string{
+	ChangeFreqAlways,
+	ChangeFreqHourly,
+	ChangeFreqDaily,
+	ChangeFreqWeekly,
+	ChangeFreqMonthly,
+	ChangeFreqYearly,
+	ChangeFreqNever,
+}
+
+// Now returns the current time. It is used for the LastMod of the sitemaps
+// written by WriteChunked and RollingWriter and by Validate, and can be
+// replaced in tests to get the same output on every run.
+var Now = time.Now
+
+// Sitemap represent a sitemap. It is safe for concurrent use by multiple
+// goroutines.
 type Sitemap struct {
+	mu    sync.Mutex
 	items []SitemapItem
+
+	// size is the number of bytes the items take up in the output
+	size int
+
+	// used are the extensions the items use, whose namespaces add to the
+	// size of the output
+	used extensions
+
+	// locs holds the index of the item of every Loc once AddUnique or Touch
+	// has been used. It is reset to nil when the items are reordered.
+	locs map[string]int
+
+	// base is the URL relative locs are resolved against, see SetBaseURL
+	base *url.URL
+
+	// lastModFormat is the layout of lastmod, see SetLastModFormat
+	lastModFormat string
+
+	// lastModPrecision is what lastmod is truncated to, whole seconds if
+	// zero, see SetLastModPrecision
+	lastModPrecision time.Duration
+
+	// maxItems is the maximum number of items, see SetMaxItems
+	maxItems int
+
+	// compact leaves out the whitespace between elements, see SetCompact
+	compact bool
+
+	// omitDeclaration leaves out the XML declaration, see
+	// SetOmitDeclaration
+	omitDeclaration bool
+
+	// customNamespaces are the xmlns attributes added with AddNamespace,
+	// keyed by prefix
+	customNamespaces map[string]string
+
+	// lastModified is the Last-Modified header of the response the sitemap
+	// was fetched with, see LastModified
+	lastModified time.Time
+
+	// priorityFunc computes the priority of items without one, see
+	// SetPriorityFunc
+	priorityFunc func(loc string) float32
+
+	// normalize normalizes the loc of added items, see SetNormalizeFunc
+	normalize func(loc string) (string, error)
+
+	// schemes are the schemes a loc may have, DefaultSchemes if nil, see
+	// SetAllowedSchemes
+	schemes []string
+
+	// lenientChangeFreq allows any changefreq, see SetLenientChangeFreq
+	lenientChangeFreq bool
 }
 
-// Add adds a sitemap item to the sitemap
-func (s *Sitemap) Add(item SitemapItem) error {
-	if len(s.items) >= MaxSitemapItems {
-		return fmt.Errorf("your sitemap has reached the maximum number of items which is %v", MaxSitemapItems)
+// DefaultSchemes are the schemes a loc may have unless a sitemap is
+// configured with SetAllowedSchemes
+var DefaultSchemes = []string{"http", "https"}
+
+// New returns an empty sitemap. The zero value of Sitemap is an empty
+// sitemap too, New is the place where internal state gets set up.
+func New() *Sitemap {
+	return &Sitemap{
+		items: make([]SitemapItem, 0),
 	}
+}
 
-	s.items = append(s.items, item)
+// SetBaseURL sets the absolute URL that a relative Loc of items added later
+// is resolved against, so that "/about" becomes baseURL's "/about" page.
+// An empty baseURL disables the resolving again.
+func (s *Sitemap) SetBaseURL(baseURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return err
+	}
+	s.base = base
 
 	return nil
 }
 
-// String return the string format of the sitemap
-func (s *Sitemap) String() string {
-	var items []string
-	for _, item := range s.items {
-		items = append(items, item.String())
+// parseBaseURL parses the base URL of SetBaseURL, which is nil if baseURL is
+// empty
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	if baseURL == "" {
+		return nil, nil
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("base URL %q is not a valid URL: %v", baseURL, err)
 	}
-	return fmt.Sprintf(SitemapXML, strings.Join(items, `
-`))
+	if !base.IsAbs() || base.Host == "" {
+		return nil, fmt.Errorf("base URL %q is not an absolute URL", baseURL)
+	}
+
+	return base, nil
 }
 
-// ToFile saves a sitemap to a file with either extension .xml or .gz.
-// If extension is .gz, the file will be gzipped.
-func (s *Sitemap) ToFile(path string) error {
-	file, err := os.Create(path)
+// resolveLoc resolves loc against base if base is not nil and loc is
+// relative
+func resolveLoc(base *url.URL, loc string) (string, error) {
+	if base == nil {
+		return loc, nil
+	}
+
+	u, err := url.Parse(loc)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("%w %q: %v", ErrInvalidLoc, loc, err)
+	}
+	if u.IsAbs() {
+		return loc, nil
 	}
-	defer file.Close()
 
-	ext := filepath.Ext(file.Name())
-	if ext != ".xml" && ext != ".gz" {
-		return fmt.Errorf("filename %s does not have extension .xml or .gz, extension %s given", file.Name(), ext)
+	return base.ResolveReference(u).String(), nil
+}
+
+// SetLastModFormat sets the layout, as in the time package, that LastMod of
+// the items is formatted with. It must produce one of the W3C datetime
+// formats, like time.DateOnly for just the date. An empty layout restores
+// the default time.RFC3339.
+func (s *Sitemap) SetLastModFormat(layout string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastModFormat = layout
+	s.resize()
+}
+
+// SetLastModPrecision sets the precision that LastMod of the items is
+// truncated to before it is formatted, so that a layout with fractional
+// seconds, like time.RFC3339Nano, does not change on every run. The default
+// is whole seconds, a precision of zero or less keeps LastMod as it is.
+func (s *Sitemap) SetLastModPrecision(precision time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastModPrecision = max(precision, time.Nanosecond)
+	s.resize()
+}
+
+// SetOmitDeclaration sets whether the XML declaration is left out of the
+// output, so that the urlset element can be embedded in another document or
+// combined with other output
+func (s *Sitemap) SetOmitDeclaration(omit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.omitDeclaration = omit
+}
+
+// SetAllowedSchemes restricts the schemes that the Loc of items may have,
+// which Add and Validate check. Schemes are compared case-insensitively.
+// Without any schemes every scheme is allowed.
+func (s *Sitemap) SetAllowedSchemes(schemes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schemes = make([]string, len(schemes))
+	for i, scheme := range schemes {
+		s.schemes[i] = strings.ToLower(scheme)
 	}
+}
 
-	// Gzip
-	if ext == ".gz" {
-		zip := gzip.NewWriter(file)
-		defer zip.Close()
+// SetLenientChangeFreq sets whether Add and Validate accept a ChangeFreq
+// that is not one of ChangeFreqs, for search systems that use their own
+// values. By default they are strict and reject such a ChangeFreq, which
+// search engines like Google ignore.
+func (s *Sitemap) SetLenientChangeFreq(lenient bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		_, err = zip.Write([]byte(s.String()))
-		if err != nil {
-			return err
-		}
-	} else {
-		_, err = file.Write([]byte(s.String()))
-		if err != nil {
-			return err
+	s.lenientChangeFreq = lenient
+}
+
+// rules returns the rules the items are validated with, s.mu must be held
+func (s *Sitemap) rules() itemRules {
+	rules := itemRules{
+		schemes:           s.schemes,
+		lenientChangeFreq: s.lenientChangeFreq,
+	}
+	if rules.schemes == nil {
+		rules.schemes = DefaultSchemes
+	}
+
+	return rules
+}
+
+// SetCompact sets whether the sitemap is written without whitespace between
+// the elements, which is insignificant in sitemaps. A compact sitemap is
+// smaller, an indented one is easier to read.
+func (s *Sitemap) SetCompact(compact bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.compact = compact
+	s.resize()
+}
+
+// resize recalculates the size of the items and the extensions they use,
+// s.mu must be held
+func (s *Sitemap) resize() {
+	s.size = 0
+	s.used = 0
+	for i, item := range s.items {
+		if i > 0 {
+			s.size += len(s.whitespace(itemSeparator))
 		}
+		s.size += len(s.format(item))
+		s.used |= item.extensions()
 	}
+}
 
-	return nil
+// format returns the string format of item, s.mu must be held
+func (s *Sitemap) format(item SitemapItem) string {
+	item.LastMod = item.LastMod.Truncate(s.precision())
+	return item.format(s.layout(), s.compact)
 }
 
-// SitemapItem represents an item in the sitemap
-type SitemapItem struct {
-	Loc        string
-	LastMod    time.Time
-	ChangeFreq string
-	Priority   float32
+// whitespace returns str, or str without its newlines and indentation if the
+// sitemap is compact, s.mu must be held
+func (s *Sitemap) whitespace(str string) string {
+	if s.compact {
+		return compactReplacer.Replace(str)
+	}
+
+	return str
 }
 
-// String return the string format of the sitemap item
-func (i *SitemapItem) String() string {
-	return fmt.Sprintf(SitemapItemXML, i.Loc, i.LastMod.Format(time.RFC3339), i.ChangeFreq, i.Priority)
+// header returns the start of the output up to the end of the urlset tag,
+// without the namespaces of the extensions. s.mu must be held.
+func (s *Sitemap) header() string {
+	header := sitemapHeader
+	if s.omitDeclaration {
+		header = strings.TrimPrefix(header, xmlDeclaration)
+	}
+
+	return s.whitespace(header)
 }
 
-// SitemapIndex is an index for multiple sitemaps
-type SitemapIndex struct {
-	items []SitemapIndexItem
+// compactReplacer removes the newlines and indentation between elements and
+// attributes
+var compactReplacer = strings.NewReplacer("\n\t", " ", "\n", "")
+
+// SetMaxItems lowers the maximum number of items of the sitemap from
+// MaxSitemapItems to n, for search engines that accept fewer items or to
+// test rollover with a handful of items. A limit of zero or less, or one
+// above MaxSitemapItems, restores MaxSitemapItems.
+func (s *Sitemap) SetMaxItems(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxItems = n
 }
 
-// Add adds a sitemap to the sitemap index
-func (s *SitemapIndex) Add(item SitemapIndexItem) {
-	s.items = append(s.items, item)
+// SetPriorityFunc sets a function that computes the priority of items added
+// without one from their loc, like DepthPriority. A nil f leaves the priority
+// of those items unset.
+func (s *Sitemap) SetPriorityFunc(f func(loc string) float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.priorityFunc = f
 }
 
-// String return the string format of the sitemap index
-func (s *SitemapIndex) String() string {
-	var items []string
+// SetNormalizeFunc sets a function that normalizes the loc of items when
+// they are added, like Normalize or StripTrailingSlash, so that different
+// spellings of the same URL end up as the same loc. A nil f leaves locs as
+// they are.
+func (s *Sitemap) SetNormalizeFunc(f func(loc string) (string, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.normalize = f
+}
+
+// DepthPriority is a priority function for SetPriorityFunc that gives the
+// home page priority 1.0 and lowers it by 0.1 for each path segment, down to
+// 0.1
+func DepthPriority(loc string) float32 {
+	u, err := url.Parse(loc)
+	if err != nil {
+		return 0.5
+	}
+
+	depth := 0
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		depth = strings.Count(p, "/") + 1
+	}
+
+	return float32(max(10-depth, 1)) / 10
+}
+
+// limit returns the maximum number of items, s.mu must be held
+func (s *Sitemap) limit() int {
+	return itemLimit(s.maxItems)
+}
+
+// itemLimit returns the maximum number of items of a sitemap limited to n
+// items, which is MaxSitemapItems if n is zero or less or above it
+func itemLimit(n int) int {
+	if n <= 0 || n > MaxSitemapItems {
+		return MaxSitemapItems
+	}
+
+	return n
+}
+
+// layout returns the layout of lastmod, s.mu must be held
+func (s *Sitemap) layout() string {
+	if s.lastModFormat == "" {
+		return time.RFC3339
+	}
+
+	return s.lastModFormat
+}
+
+// precision returns the precision of lastmod, s.mu must be held
+func (s *Sitemap) precision() time.Duration {
+	if s.lastModPrecision == 0 {
+		return time.Second
+	}
+
+	return s.lastModPrecision
+}
+
+// Add adds a sitemap item to the sitemap
+func (s *Sitemap) Add(item SitemapItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.add(item)
+}
+
+// AddURL adds an item with only the given loc to the sitemap
+func (s *Sitemap) AddURL(loc string) error {
+	return s.Add(SitemapItem{Loc: loc})
+}
+
+// AddAll adds the items to the sitemap, stopping at the first item that
+// cannot be added. The items before it stay in the sitemap. Nothing is added
+// if the items would exceed the maximum number of items.
+func (s *Sitemap) AddAll(items []SitemapItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if total := len(s.items) + len(items); total > s.limit() {
+		return fmt.Errorf("%w, adding %d items would grow your sitemap to %d items, more than %d", ErrMaxItemsExceeded, len(items), total, s.limit())
+	}
+
+	s.items = slices.Grow(s.items, len(items))
+	for i, item := range items {
+		if err := s.add(item); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// AddBounded adds a sitemap item to a sitemap that keeps the most recently
+// modified items, sorted like SortByLastMod does. Once the sitemap has the
+// maximum number of items, the oldest item is removed to make room, or the
+// item is not added if it is older than all of them. It reports whether the
+// item was added. Items added in another way must be sorted with
+// SortByLastMod first.
+func (s *Sitemap) AddBounded(item SitemapItem) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loc, err := s.resolve(item.Loc)
+	if err != nil {
+		return false, err
+	}
+	item.Loc = loc
+
+	var evicted *SitemapItem
+	if n := len(s.items); n > 0 && n >= s.limit() {
+		oldest := s.items[n-1]
+		if compareLastMod(item, oldest) >= 0 {
+			return false, nil
+		}
+
+		evicted = &oldest
+		s.remove(n - 1)
+	}
+
+	if err := s.add(item); err != nil {
+		if evicted != nil {
+			s.items = append(s.items, *evicted)
+			s.resize()
+		}
+		return false, err
+	}
+
+	// Move the item from the end to its place
+	item = s.items[len(s.items)-1]
+	i, _ := slices.BinarySearchFunc(s.items[:len(s.items)-1], item, compareLastMod)
+	copy(s.items[i+1:], s.items[i:len(s.items)-1])
+	s.items[i] = item
+	s.locs = nil
+
+	return true, nil
+}
+
+// remove removes the item at i, s.mu must be held
+func (s *Sitemap) remove(i int) {
+	if len(s.items) > 1 {
+		s.size -= len(s.whitespace(itemSeparator))
+	}
+	s.size -= len(s.format(s.items[i]))
+	s.locs = nil
+
+	s.items = slices.Delete(s.items, i, i+1)
+
+	s.used = 0
 	for _, item := range s.items {
-		items = append(items, item.String())
+		s.used |= item.extensions()
+	}
+}
+
+// indexLocs returns the index of the item of every Loc, building it if
+// needed. s.mu must be held.
+func (s *Sitemap) indexLocs() map[string]int {
+	if s.locs == nil {
+		s.locs = make(map[string]int, len(s.items))
+		for i, item := range s.items {
+			if _, ok := s.locs[item.Loc]; !ok {
+				s.locs[item.Loc] = i
+			}
+		}
 	}
 
-	return fmt.Sprintf(SitemapIndexXML, strings.Join(items, `
-`))
+	return s.locs
 }
 
-// SitemapIndexItem represents an item in the sitemap index
-type SitemapIndexItem struct {
-	Loc     string
-	LastMod time.Time
+// Touch sets the LastMod of the item with the given loc to t, resolving loc
+// like Add does. It reports whether there is such an item. The lookup is a
+// map lookup after the first call, as long as the items are not reordered.
+func (s *Sitemap) Touch(loc string, t time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loc, err := s.resolve(loc)
+	if err != nil {
+		return false
+	}
+
+	i, ok := s.indexLocs()[loc]
+	if !ok {
+		return false
+	}
+
+	s.size -= len(s.format(s.items[i]))
+	s.items[i].LastMod = t
+	s.size += len(s.format(s.items[i]))
+
+	return true
 }
 
-// String return the string format of the sitemap item
-func (i *SitemapIndexItem) String() string {
-	return fmt.Sprintf(SitemapIndexItemXML, i.Loc, i.LastMod.Format(time.RFC3339))
+// AddUnique adds a sitemap item to the sitemap unless there already is an
+// item with the same Loc. It reports whether the item was added.
+func (s *Sitemap) AddUnique(item SitemapItem) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loc, err := s.resolve(item.Loc)
+	if err != nil {
+		return false, err
+	}
+	item.Loc = loc
+
+	if _, ok := s.indexLocs()[item.Loc]; ok {
+		return false, nil
+	}
+
+	if err := s.add(item); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
-// ToFile saves a sitemap index to a file with either extension .xml or .gz.
-// If extension is .gz, the file will be gzipped.
-func (s *SitemapIndex) ToFile(path string) error {
-	file, err := os.Create(path)
+// add adds a sitemap item to the sitemap, s.mu must be held
+func (s *Sitemap) add(item SitemapItem) error {
+	loc, err := s.resolve(item.Loc)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	item.Loc = loc
 
-	ext := filepath.Ext(file.Name())
-	if ext != ".xml" && ext != ".gz" {
-		return fmt.Errorf("filename %s does not have extension .xml or .gz, extension %s given", file.Name(), ext)
+	if len(s.items) >= s.limit() {
+		return fmt.Errorf("%w, your sitemap has reached the maximum of %d items", ErrMaxItemsExceeded, s.limit())
 	}
 
-	// Gzip
-	if ext == ".gz" {
-		zip := gzip.NewWriter(file)
-		defer zip.Close()
+	if item.Priority == nil && s.priorityFunc != nil {
+		item.Priority = Priority(s.priorityFunc(item.Loc))
+	}
 
-		_, err = zip.Write([]byte(s.String()))
-		if err != nil {
-			return err
+	if err := item.validate(s.rules()); err != nil {
+		return err
+	}
+
+	size := len(s.format(item))
+	if len(s.items) > 0 {
+		size += len(s.whitespace(itemSeparator))
+	}
+	used := s.used | item.extensions()
+	if total := s.total(s.size+size, used); total > MaxUncompressedBytes {
+		return fmt.Errorf("%w, adding %s would grow the sitemap to %d bytes, more than %d", ErrMaxSizeExceeded, item.Loc, total, MaxUncompressedBytes)
+	}
+
+	s.items = append(s.items, item)
+	s.size += size
+	s.used = used
+	if _, ok := s.locs[item.Loc]; s.locs != nil && !ok {
+		s.locs[item.Loc] = len(s.items) - 1
+	}
+
+	return nil
+}
+
+// resolve resolves loc against the base URL if there is one and loc is
+// relative, and normalizes it if a normalize function is set. s.mu must be
+// held.
+func (s *Sitemap) resolve(loc string) (string, error) {
+	loc, err := resolveLoc(s.base, loc)
+	if err != nil {
+		return "", err
+	}
+
+	if s.normalize != nil {
+		return s.normalize(loc)
+	}
+
+	return loc, nil
+}
+
+// Merge appends the items of other to the sitemap. Nothing is appended if
+// the merged sitemap would exceed MaxSitemapItems or the maximum size.
+// Items that are in both sitemaps end up in it twice.
+func (s *Sitemap) Merge(other *Sitemap) error {
+	other.mu.Lock()
+	items := slices.Clone(other.items)
+	other.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if total := len(s.items) + len(items); total > s.limit() {
+		return fmt.Errorf("%w, merging would grow your sitemap to %d items, more than %d", ErrMaxItemsExceeded, total, s.limit())
+	}
+
+	// The size of the items depends on the lastmod layout and compactness,
+	// which may differ between the sitemaps
+	size := 0
+	used := s.used
+	for i, item := range items {
+		if i > 0 || len(s.items) > 0 {
+			size += len(s.whitespace(itemSeparator))
 		}
-	} else {
-		_, err = file.Write([]byte(s.String()))
-		if err != nil {
-			return err
+		size += len(s.format(item))
+		used |= item.extensions()
+	}
+	if total := s.total(s.size+size, used); total > MaxUncompressedBytes {
+		return fmt.Errorf("%w, merging would grow the sitemap to %d bytes, more than %d", ErrMaxSizeExceeded, total, MaxUncompressedBytes)
+	}
+
+	if s.locs != nil {
+		for i, item := range items {
+			if _, ok := s.locs[item.Loc]; !ok {
+				s.locs[item.Loc] = len(s.items) + i
+			}
 		}
 	}
+	s.items = append(s.items, items...)
+	s.size += size
+	s.used = used
 
 	return nil
 }
 
-// NewIndexFromDir creates a sitemap index by scanning a folder for files.
-// The files modified time will be used as LastMod.
-func NewIndexFromDir(dir, pathPrefix, filenamePrefix string) (*SitemapIndex, error) {
-	s := &SitemapIndex{
-		make([]SitemapIndexItem, 0),
+// Validate checks the whole sitemap against the sitemap protocol: the
+// number of items, the size and every item. It also reports items with a
+// LastMod in the future, which search engines distrust. The returned error
+// combines all problems found.
+func (s *Sitemap) Validate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	if len(s.items) == 0 {
+		errs = append(errs, fmt.Errorf("%w, a sitemap must have at least one", ErrEmptySitemap))
+	}
+	if len(s.items) > s.limit() {
+		errs = append(errs, fmt.Errorf("%w, sitemap has %d items, more than %d", ErrMaxItemsExceeded, len(s.items), s.limit()))
 	}
 
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return s, err
+	if size, _ := s.writeTo(io.Discard); size > MaxUncompressedBytes {
+		errs = append(errs, fmt.Errorf("%w, sitemap is %d bytes, more than %d", ErrMaxSizeExceeded, size, MaxUncompressedBytes))
 	}
 
-	for _, file := range files {
-		ext := filepath.Ext(file.Name())
-		if strings.HasPrefix(file.Name(), filenamePrefix) && (ext == ".xml" || ext == ".gz") {
-			var sitemapPath string
-			if pathPrefix != "" {
-				sitemapPath = pathPrefix + file.Name()
-			} else {
-				sitemapPath = path.Join(dir, file.Name())
-			}
-			item := SitemapIndexItem{
-				sitemapPath,
-				file.ModTime(),
-			}
+	latest := Now().Add(futureLastModTolerance)
+	for i, item := range s.items {
+		if err := item.validate(s.rules()); err != nil {
+			errs = append(errs, fmt.Errorf("item %d: %w", i, err))
+		}
+		if item.LastMod.After(latest) {
+			errs = append(errs, fmt.Errorf("item %d: %w, %s of %s", i, ErrFutureLastMod, item.LastMod.Format(time.RFC3339), item.Loc))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reset removes all items from the sitemap but keeps the allocated memory
+// and the settings, so the sitemap can be reused for another set of items
+func (s *Sitemap) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.items)
+	s.items = s.items[:0]
+	s.size = 0
+	s.used = 0
+	clear(s.locs)
+}
 
-			s.Add(item)
+// Filter removes the items for which keep returns false from the sitemap,
+// keeping the order of the remaining items
+func (s *Sitemap) Filter(keep func(SitemapItem) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, item := range s.items {
+		if keep(item) {
+			s.items[n] = item
+			n++
 		}
 	}
+	clear(s.items[n:])
+	s.items = s.items[:n]
 
-	return s, nil
+	s.resize()
+	s.locs = nil
+}
+
+// Equal reports whether both sitemaps have equal items in the same order,
+// like SitemapItem.Equal but with LastMod formatted the way both sitemaps
+// write it, see SetLastModFormat and SetLastModPrecision. Other settings
+// like the base URL are not compared.
+func (s *Sitemap) Equal(other *Sitemap) bool {
+	s.mu.Lock()
+	items, layout, precision := slices.Clone(s.items), s.layout(), s.precision()
+	s.mu.Unlock()
+
+	other.mu.Lock()
+	otherItems, otherLayout := slices.Clone(other.items), other.layout()
+	precision = max(precision, other.precision())
+	other.mu.Unlock()
+
+	return slices.EqualFunc(items, otherItems, func(item, otherItem SitemapItem) bool {
+		return item.equal(otherItem, layout, precision) && item.equal(otherItem, otherLayout, precision)
+	})
+}
+
+// Len returns the number of items in the sitemap
+func (s *Sitemap) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items)
+}
+
+// Items returns an iterator over the items of the sitemap. It iterates over
+// a copy, so the sitemap can be changed while iterating.
+func (s *Sitemap) Items() iter.Seq[SitemapItem] {
+	s.mu.Lock()
+	items := slices.Clone(s.items)
+	s.mu.Unlock()
+
+	return slices.Values(items)
+}
+
+// Clone returns a deep copy of the sitemap with the same items and settings,
+// which can be changed without affecting s
+func (s *Sitemap) Clone() *Sitemap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := s.emptyCopy()
+	clone.items = make([]SitemapItem, len(s.items))
+	for i, item := range s.items {
+		clone.items[i] = item.clone()
+	}
+	clone.size = s.size
+	clone.used = s.used
+	clone.locs = maps.Clone(s.locs)
+	clone.lastModified = s.lastModified
+
+	return clone
+}
+
+// Split divides the items of the sitemap into sitemaps that each stay within
+// the maximum number of items and the maximum size, keeping their order. The
+// sitemaps have the same settings as s. A sitemap within the limits is
+// returned as a single sitemap.
+func (s *Sitemap) Split() []*Sitemap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var chunks []*Sitemap
+	chunk := s.emptyCopy()
+	for _, item := range s.items {
+		if len(chunk.items) > 0 && !chunk.fits(item) {
+			chunks = append(chunks, chunk)
+			chunk = s.emptyCopy()
+		}
+		chunk.push(item)
+	}
+
+	return append(chunks, chunk)
+}
+
+// fits reports whether item can be added within the maximum number of items
+// and the maximum size, s.mu must be held
+func (s *Sitemap) fits(item SitemapItem) bool {
+	size := s.size + len(s.format(item))
+	if len(s.items) > 0 {
+		size += len(s.whitespace(itemSeparator))
+	}
+
+	return len(s.items) < s.limit() && s.total(size, s.used|item.extensions()) <= MaxUncompressedBytes
+}
+
+// push appends item as it is, without resolving or checking it. s.mu must
+// be held.
+func (s *Sitemap) push(item SitemapItem) {
+	if len(s.items) > 0 {
+		s.size += len(s.whitespace(itemSeparator))
+	}
+	s.size += len(s.format(item))
+	s.used |= item.extensions()
+	s.items = append(s.items, item)
+}
+
+// SplitByLanguage divides items into one sitemap per language, keyed by the
+// language langOf returns for each item, as an alternative to listing the
+// language versions of a page as Alternates on every item. The Alternates
+// of the items are left out. NewIndex creates the index of the sitemaps.
+func SplitByLanguage(items []SitemapItem, langOf func(SitemapItem) string) (map[string]*Sitemap, error) {
+	sitemaps := make(map[string]*Sitemap)
+	for i, item := range items {
+		lang := langOf(item)
+		s, ok := sitemaps[lang]
+		if !ok {
+			s = New()
+			sitemaps[lang] = s
+		}
+
+		item.Alternates = nil
+		if err := s.Add(item); err != nil {
+			return sitemaps, fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+
+	return sitemaps, nil
+}
+
+// emptyCopy returns a sitemap without items with the settings of s, s.mu
+// must be held
+func (s *Sitemap) emptyCopy() *Sitemap {
+	return &Sitemap{
+		base:              s.base,
+		lastModFormat:     s.lastModFormat,
+		lastModPrecision:  s.lastModPrecision,
+		maxItems:          s.maxItems,
+		priorityFunc:      s.priorityFunc,
+		normalize:         s.normalize,
+		compact:           s.compact,
+		omitDeclaration:   s.omitDeclaration,
+		customNamespaces:  maps.Clone(s.customNamespaces),
+		schemes:           s.schemes,
+		lenientChangeFreq: s.lenientChangeFreq,
+	}
+}
+
+// LatestMod returns the newest LastMod of the items, or the zero time if no
+// item has one
+func (s *Sitemap) LatestMod() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest time.Time
+	for _, item := range s.items {
+		if item.LastMod.After(latest) {
+			latest = item.LastMod
+		}
+	}
+
+	return latest
+}
+
+// LastModified returns the time of the Last-Modified header of the response
+// a sitemap was fetched with by Fetch, or the zero time if there was none.
+// It can stand in for LastMod on sitemaps that leave it out.
+func (s *Sitemap) LastModified() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastModified
+}
+
+// Size returns the number of bytes String would return, without rendering
+// the sitemap
+func (s *Sitemap) Size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var used extensions
+	for _, item := range s.items {
+		used |= item.extensions()
+	}
+
+	return s.total(s.size, used)
+}
+
+// total returns the size of the output with items of the given size that
+// use the given extensions, s.mu must be held
+func (s *Sitemap) total(size int, used extensions) int {
+	return len(s.header()) + s.namespacesSize(used) + size + len(s.whitespace(sitemapFooter))
+}
+
+// Sort sorts the items by Loc, so that the same set of items always gives
+// the same output
+func (s *Sitemap) Sort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sort.SliceStable(s.items, func(i, j int) bool {
+		return s.items[i].Loc < s.items[j].Loc
+	})
+	s.locs = nil
+}
+
+// SortByLastMod sorts the items by LastMod, the most recently modified
+// first. Items with the same LastMod are sorted by Loc.
+func (s *Sitemap) SortByLastMod() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slices.SortStableFunc(s.items, compareLastMod)
+	s.locs = nil
+}
+
+// compareLastMod orders a before b if it was modified more recently, or by
+// Loc if both have the same LastMod
+func compareLastMod(a, b SitemapItem) int {
+	if c := b.LastMod.Compare(a.LastMod); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.Loc, b.Loc)
+}
+
+// String return the string format of the sitemap
+func (s *Sitemap) String() string {
+	var b strings.Builder
+	s.WriteTo(&b)
+	return b.String()
+}
+
+// WriteTo writes the sitemap to w one item at a time. It implements
+// io.WriterTo.
+func (s *Sitemap) WriteTo(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writeTo(w)
+}
+
+// writeTo writes the sitemap to w, s.mu must be held
+func (s *Sitemap) writeTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	cw.WriteString(strings.TrimSuffix(s.header(), ">"))
+	for _, attr := range s.namespaces() {
+		cw.WriteString(s.whitespace(attr))
+	}
+	cw.WriteString(">")
+	for i, item := range s.items {
+		if i > 0 {
+			cw.WriteString(s.whitespace(itemSeparator))
+		}
+		cw.WriteString(s.format(item))
+	}
+	cw.WriteString(s.whitespace(sitemapFooter))
+
+	return cw.n, cw.err
+}
+
+// ToFile saves a sitemap to a file named *.xml or *.xml.gz. A *.xml.gz
+// file will be gzipped. A sitemap without items is not saved, search engines
+// reject those.
+func (s *Sitemap) ToFile(path string) error {
+	return s.toFS(context.Background(), osFS{}, path, gzip.DefaultCompression)
+}
+
+// ToFileContext is like ToFile but stops writing with the error of ctx once
+// it is done.
+func (s *Sitemap) ToFileContext(ctx context.Context, path string) error {
+	return s.toFS(ctx, osFS{}, path, gzip.DefaultCompression)
+}
+
+// ToFileWithLevel is like ToFile but gzips the file with the given
+// compression level, which is one of the compress/gzip levels. Any other
+// level fails, also for a file that is not gzipped.
+func (s *Sitemap) ToFileWithLevel(path string, level int) error {
+	return s.toFS(context.Background(), osFS{}, path, level)
+}
+
+// WriteToFS saves the sitemap to the file name in fsys like ToFile does to
+// the OS filesystem
+func (s *Sitemap) WriteToFS(fsys WriteFS, name string) error {
+	return s.toFS(context.Background(), fsys, name, gzip.DefaultCompression)
+}
+
+// ToHashedFile is like ToFile but adds a short hash of the content of the
+// sitemap to the filename before the extension, so that sitemap.xml becomes
+// sitemap-0123abcd.xml. The name changes whenever the content does, so a
+// CDN never serves a stale copy. It returns the path of the written file.
+func (s *Sitemap) ToHashedFile(path string) (string, error) {
+	if _, err := isGzipName(path); err != nil {
+		return "", err
+	}
+
+	path = hashedName(path, s.hash())
+
+	return path, s.ToFile(path)
+}
+
+// ToFileAuto is like ToFile but decides whether to gzip the sitemap by its
+// size: a sitemap of more than threshold bytes is gzipped and .gz is
+// appended to path, a smaller one is written as plain XML and a .gz
+// extension is removed. It returns the path of the written file.
+func (s *Sitemap) ToFileAuto(path string, threshold int) (string, error) {
+	if _, err := isGzipName(path); err != nil {
+		return "", err
+	}
+
+	path = strings.TrimSuffix(path, ".gz")
+	if s.Size() > threshold {
+		path += ".gz"
+	}
+
+	return path, s.ToFile(path)
+}
+
+// hash returns the first 8 hex characters of the SHA-256 hash of the
+// uncompressed sitemap
+func (s *Sitemap) hash() string {
+	h := sha256.New()
+	s.WriteTo(h)
+
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// hashedName adds hash to name before the extension .xml or .xml.gz
+func hashedName(name, hash string) string {
+	ext := ".xml"
+	if strings.HasSuffix(name, ".xml.gz") {
+		ext = ".xml.gz"
+	}
+
+	return strings.TrimSuffix(name, ext) + "-" + hash + ext
+}
+
+// ToXMLFile saves the sitemap uncompressed to path, whatever its extension
+func (s *Sitemap) ToXMLFile(path string) error {
+	return s.save(context.Background(), osFS{}, path, false, gzip.DefaultCompression)
+}
+
+// ToGzipFile saves the sitemap gzipped to path, whatever its extension.
+// The extension .gz is appended to path if it does not end in it.
+func (s *Sitemap) ToGzipFile(path string) error {
+	if !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+
+	return s.save(context.Background(), osFS{}, path, true, gzip.DefaultCompression)
+}
+
+// toFS saves the sitemap to the file name in fsys, gzipped if name ends in
+// .xml.gz
+func (s *Sitemap) toFS(ctx context.Context, fsys WriteFS, name string, level int) error {
+	gzipped, err := isGzipName(name)
+	if err != nil {
+		return err
+	}
+
+	return s.save(ctx, fsys, name, gzipped, level)
+}
+
+// save saves the sitemap to the file name in fsys, refusing to write a
+// sitemap without items since search engines reject those
+func (s *Sitemap) save(ctx context.Context, fsys WriteFS, name string, gzipped bool, level int) error {
+	if s.Len() == 0 {
+		return fmt.Errorf("could not save %s: %w", name, ErrEmptySitemap)
+	}
+
+	return saveFile(ctx, fsys, name, s, gzipped, level)
+}
+
+// SitemapItem represents an item in the sitemap. LastMod, ChangeFreq and
+// Priority are optional and are left out of the output when unset.
+type SitemapItem struct {
+	Loc        string    `xml:"loc"`
+	LastMod    time.Time `xml:"lastmod,omitempty"`
+	ChangeFreq string    `xml:"changefreq,omitempty"`
+	Priority   *float32  `xml:"priority,omitempty"`
+
+	// Images are the images on the page, see Image
+	Images []Image `xml:"image:image"`
+
+	// Videos are the videos on the page, see Video
+	Videos []Video `xml:"video:video"`
+
+	// News marks the page as a news article, see NewsInfo. Google ignores
+	// changefreq and priority in news sitemaps, so they are left out of
+	// items with News set.
+	News *NewsInfo `xml:"news:news"`
+
+	// Alternates are the language versions of the page, see Alternate
+	Alternates []Alternate `xml:"xhtml:link"`
+
+	// Mobile marks the page as made for mobile devices with an empty
+	// mobile:mobile element
+	Mobile bool `xml:"mobile:mobile"`
+
+	// Geo marks the page as geo content, see GeoInfo
+	Geo *GeoInfo `xml:"geo:geo"`
+
+	// Extra is raw XML written at the end of the url element, for elements
+	// the package does not support. It is NOT escaped or checked, the caller
+	// is responsible for it being well-formed. Declare the namespaces it uses
+	// with Sitemap.AddNamespace. Parse leaves it empty.
+	Extra string `xml:",innerxml"`
+}
+
+// Priority returns a pointer to p, for use as SitemapItem.Priority
+func Priority(p float32) *float32 {
+	return &p
+}
+
+// clone returns a copy of the item that shares no memory with i
+func (i SitemapItem) clone() SitemapItem {
+	if i.Priority != nil {
+		i.Priority = Priority(*i.Priority)
+	}
+	if i.News != nil {
+		news := *i.News
+		i.News = &news
+	}
+	if i.Geo != nil {
+		geo := *i.Geo
+		i.Geo = &geo
+	}
+	i.Images = slices.Clone(i.Images)
+	i.Videos = slices.Clone(i.Videos)
+	i.Alternates = slices.Clone(i.Alternates)
+
+	return i
+}
+
+// ItemFromFile returns an item for the page at loc with the modification
+// time of the file at filePath as LastMod, like NewIndexFromDir does for
+// sitemaps
+func ItemFromFile(loc, filePath string) (SitemapItem, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return SitemapItem{}, err
+	}
+
+	return SitemapItem{
+		Loc:     loc,
+		LastMod: info.ModTime(),
+	}, nil
+}
+
+// FromFS creates a sitemap of the pages of a static site in fsys. Every file
+// for which include returns true, or every .html file if include is nil,
+// becomes an item with its path joined to baseURL as Loc and its
+// modification time as LastMod. An index.html file is listed as its
+// folder with a trailing slash, the way it is served.
+func FromFS(fsys fs.FS, baseURL string, include func(path string) bool) (*Sitemap, error) {
+	if include == nil {
+		include = func(p string) bool {
+			return path.Ext(p) == ".html"
+		}
+	}
+
+	s := New()
+	err := fs.WalkDir(fsys, ".", func(p string, file fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file.IsDir() || !include(p) {
+			return nil
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			return err
+		}
+
+		name := p
+		if path.Base(p) == "index.html" {
+			// Folders keep their trailing slash, the root one too
+			name = "/" + strings.TrimSuffix(p, "index.html")
+		}
+		loc, err := joinURL(baseURL, name)
+		if err != nil {
+			return err
+		}
+
+		return s.Add(SitemapItem{
+			Loc:     loc,
+			LastMod: info.ModTime(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// String return the string format of the sitemap item
+func (i *SitemapItem) String() string {
+	return i.format(time.RFC3339, false)
+}
+
+// Equal reports whether the item is written the same as other, so that an
+// item equals itself after it was written and parsed again even though
+// LastMod is written in seconds and Priority with one decimal
+func (i SitemapItem) Equal(other SitemapItem) bool {
+	return i.equal(other, time.RFC3339, time.Second)
+}
+
+// equal reports whether the item is written the same as other with LastMod
+// truncated to precision and formatted with layout
+func (i SitemapItem) equal(other SitemapItem, layout string, precision time.Duration) bool {
+	i.LastMod = i.LastMod.Truncate(precision)
+	other.LastMod = other.LastMod.Truncate(precision)
+
+	return i.format(layout, true) == other.format(layout, true)
+}
+
+// format returns the string format of the sitemap item with LastMod
+// formatted with layout, without any whitespace if compact is set
+func (i *SitemapItem) format(layout string, compact bool) string {
+	if i.plain() {
+		return i.formatPlain(layout, compact)
+	}
+
+	if compact {
+		b, _ := xml.Marshal(i.wire(layout))
+		return string(b)
+	}
+
+	b, _ := xml.MarshalIndent(i.wire(layout), "\t", "\t")
+	return "\n" + string(b)
+}
+
+// MarshalXML encodes the item as a url element. It implements xml.Marshaler
+// to format LastMod and Priority the way the protocol expects them.
+func (i SitemapItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(i.wire(time.RFC3339))
+}
+
+// Validate checks the item against the sitemap protocol: that Loc is an
+// absolute URL with one of DefaultSchemes within the length limit, that
+// Priority is within [0.0, 1.0], that ChangeFreq is one of ChangeFreqs and
+// that the extensions are complete. The returned error combines all
+// problems found.
+func (i SitemapItem) Validate() error {
+	return i.validate(itemRules{schemes: DefaultSchemes})
+}
+
+// itemRules are the settings of a sitemap that change how its items are
+// validated
+type itemRules struct {
+	// schemes are the schemes Loc may have, any if there are none
+	schemes []string
+
+	// lenientChangeFreq allows a ChangeFreq that is not one of ChangeFreqs
+	lenientChangeFreq bool
+}
+
+// validate is Validate with the rules of a sitemap
+func (i SitemapItem) validate(rules itemRules) error {
+	var errs []error
+	if err := validateLoc(i.Loc, rules.schemes); err != nil {
+		errs = append(errs, err)
+	}
+
+	if i.Priority != nil && !(*i.Priority >= 0 && *i.Priority <= 1) {
+		errs = append(errs, fmt.Errorf("%w %.1f, it must be between 0.0 and 1.0", ErrInvalidPriority, *i.Priority))
+	}
+
+	if i.ChangeFreq != "" && !rules.lenientChangeFreq && !validChangeFreq(i.ChangeFreq) {
+		errs = append(errs, fmt.Errorf("%w %q, it must be one of %s", ErrInvalidChangeFreq, i.ChangeFreq, strings.Join(ChangeFreqs, ", ")))
+	}
+
+	for _, video := range i.Videos {
+		if err := video.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid video on %s: %v", i.Loc, err))
+		}
+	}
+
+	if i.News != nil {
+		if err := i.News.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid news on %s: %v", i.Loc, err))
+		}
+	}
+
+	if i.Geo != nil {
+		if err := i.Geo.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid geo on %s: %v", i.Loc, err))
+		}
+	}
+
+	for _, alternate := range i.Alternates {
+		if err := alternate.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid alternate on %s: %v", i.Loc, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateLoc checks that loc is an absolute URL within the length limit
+// with one of schemes, or any scheme if there are none
+func validateLoc(loc string, schemes []string) error {
+	if len(loc) > MaxURLLength {
+		return fmt.Errorf("%w %s, it is longer than the maximum of %d characters", ErrInvalidLoc, loc, MaxURLLength)
+	}
+
+	u, err := url.Parse(loc)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidLoc, loc, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("%w %q, it is not an absolute URL", ErrInvalidLoc, loc)
+	}
+	if len(schemes) > 0 && !slices.Contains(schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w %q, its scheme %s is not one of %s", ErrInvalidLoc, loc, u.Scheme, strings.Join(schemes, ", "))
+	}
+
+	return nil
+}
+
+// validChangeFreq reports whether changeFreq is one of ChangeFreqs
+func validChangeFreq(changeFreq string) bool {
+	for _, valid := range ChangeFreqs {
+		if changeFreq == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SitemapIndex is an index for multiple sitemaps
+type SitemapIndex struct {
+	items []SitemapIndexItem
+
+	// base is the URL relative locs are resolved against, see SetBaseURL
+	base *url.URL
+
+	// compact leaves out the whitespace between elements, see SetCompact
+	compact bool
+}
+
+// NewSitemapIndex returns an empty sitemap index
+func NewSitemapIndex() *SitemapIndex {
+	return &SitemapIndex{
+		items: make([]SitemapIndexItem, 0),
+	}
+}
+
+// SetBaseURL sets the absolute URL that a relative Loc of sitemaps added
+// later is resolved against, like Sitemap.SetBaseURL does for items
+func (s *SitemapIndex) SetBaseURL(baseURL string) error {
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return err
+	}
+	s.base = base
+
+	return nil
+}
+
+// SetCompact sets whether the sitemap index is written without whitespace
+// between the elements, like Sitemap.SetCompact, so that both can be
+// written the same way
+func (s *SitemapIndex) SetCompact(compact bool) {
+	s.compact = compact
+}
+
+// Add adds a sitemap to the sitemap index without checking it, see
+// AddChecked. A relative Loc is resolved against the base URL if there is
+// one, a Loc that can not be resolved is added as it is.
+func (s *SitemapIndex) Add(item SitemapIndexItem) {
+	if loc, err := resolveLoc(s.base, item.Loc); err == nil {
+		item.Loc = loc
+	}
+
+	s.items = append(s.items, item)
+}
+
+// AddSitemap adds sitemap to the sitemap index with Add. Its lastmod is the
+// newest LastMod of the items of sitemap, so that it reflects when the
+// content last changed rather than when the file was written. loc may be a
+// filename that is resolved against the base URL.
+func (s *SitemapIndex) AddSitemap(loc string, sitemap *Sitemap) {
+	s.Add(SitemapIndexItem{
+		Loc:     loc,
+		LastMod: sitemap.LatestMod(),
+	})
+}
+
+// AddChecked adds a sitemap to the sitemap index like Sitemap.Add does
+// items: it fails if the index already has the maximum of 50,000 sitemaps or
+// if Loc is not an absolute URL
+func (s *SitemapIndex) AddChecked(item SitemapIndexItem) error {
+	if len(s.items) >= MaxSitemapIndexItems {
+		return fmt.Errorf("%w, your sitemap index has reached the maximum of %d sitemaps", ErrMaxItemsExceeded, MaxSitemapIndexItems)
+	}
+
+	loc, err := resolveLoc(s.base, item.Loc)
+	if err != nil {
+		return err
+	}
+	item.Loc = loc
+
+	if err := validateLoc(item.Loc, DefaultSchemes); err != nil {
+		return err
+	}
+
+	s.items = append(s.items, item)
+
+	return nil
+}
+
+// Clone returns a copy of the sitemap index that can be changed without
+// affecting s
+func (s *SitemapIndex) Clone() *SitemapIndex {
+	return &SitemapIndex{
+		items:   slices.Clone(s.items),
+		base:    s.base,
+		compact: s.compact,
+	}
+}
+
+// Merge appends the sitemaps of other to the sitemap index. Nothing is
+// appended if the merged sitemap index would exceed MaxSitemapIndexItems.
+func (s *SitemapIndex) Merge(other *SitemapIndex) error {
+	if total := len(s.items) + len(other.items); total > MaxSitemapIndexItems {
+		return fmt.Errorf("%w, merging would grow your sitemap index to %d sitemaps, more than %d", ErrMaxItemsExceeded, total, MaxSitemapIndexItems)
+	}
+
+	s.items = append(s.items, other.items...)
+
+	return nil
+}
+
+// Validate checks the whole sitemap index against the sitemap protocol: the
+// number of sitemaps and that every Loc is an absolute URL. The returned
+// error combines all problems found.
+func (s *SitemapIndex) Validate() error {
+	var errs []error
+	if len(s.items) > MaxSitemapIndexItems {
+		errs = append(errs, fmt.Errorf("%w, sitemap index has %d sitemaps, more than %d", ErrMaxItemsExceeded, len(s.items), MaxSitemapIndexItems))
+	}
+
+	for i, item := range s.items {
+		if err := validateLoc(item.Loc, DefaultSchemes); err != nil {
+			errs = append(errs, fmt.Errorf("sitemap %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Len returns the number of sitemaps in the sitemap index
+func (s *SitemapIndex) Len() int {
+	return len(s.items)
+}
+
+// Split divides the sitemaps of the index into indexes of at most 50,000
+// sitemaps each, keeping their order, so that they can be referenced from
+// another index. An index within the limit is returned as a single index.
+func (s *SitemapIndex) Split() []*SitemapIndex {
+	var chunks []*SitemapIndex
+	for items := range slices.Chunk(s.items, MaxSitemapIndexItems) {
+		chunks = append(chunks, &SitemapIndex{
+			items:   slices.Clone(items),
+			base:    s.base,
+			compact: s.compact,
+		})
+	}
+
+	if len(chunks) == 0 {
+		chunks = append(chunks, &SitemapIndex{
+			items:   make([]SitemapIndexItem, 0),
+			base:    s.base,
+			compact: s.compact,
+		})
+	}
+
+	return chunks
+}
+
+// Items returns an iterator over the items of the sitemap index. It
+// iterates over a copy, so the sitemap index can be changed while iterating.
+func (s *SitemapIndex) Items() iter.Seq[SitemapIndexItem] {
+	return slices.Values(slices.Clone(s.items))
+}
+
+// String return the string format of the sitemap index
+func (s *SitemapIndex) String() string {
+	var b strings.Builder
+	s.WriteTo(&b)
+	return b.String()
+}
+
+// WriteTo writes the sitemap index to w one item at a time. It implements
+// io.WriterTo.
+func (s *SitemapIndex) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	cw.WriteString(s.whitespace(sitemapIndexHeader))
+	for i, item := range s.items {
+		if i > 0 {
+			cw.WriteString(s.whitespace(itemSeparator))
+		}
+		cw.WriteString(item.format(s.compact))
+	}
+	cw.WriteString(s.whitespace(sitemapIndexFooter))
+
+	return cw.n, cw.err
+}
+
+// whitespace returns str without whitespace if the index is compact
+func (s *SitemapIndex) whitespace(str string) string {
+	if s.compact {
+		return compactReplacer.Replace(str)
+	}
+
+	return str
+}
+
+// SitemapIndexItem represents an item in the sitemap index
+type SitemapIndexItem struct {
+	Loc     string    `xml:"loc"`
+	LastMod time.Time `xml:"lastmod,omitempty"`
+}
+
+// String return the string format of the sitemap item
+func (i *SitemapIndexItem) String() string {
+	return i.format(false)
+}
+
+// format returns the string format of the item, without any whitespace if
+// compact is set
+func (i *SitemapIndexItem) format(compact bool) string {
+	if compact {
+		b, _ := xml.Marshal(i)
+		return string(b)
+	}
+
+	b, _ := xml.MarshalIndent(i, "\t", "\t")
+	return "\n" + string(b)
+}
+
+// MarshalXML encodes the item as a sitemap element. It implements
+// xml.Marshaler to format LastMod the way the protocol expects it.
+func (i SitemapIndexItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(i.wire())
+}
+
+// ToFile saves a sitemap index to a file named *.xml or *.xml.gz. A *.xml.gz
+// file will be gzipped.
+func (s *SitemapIndex) ToFile(path string) error {
+	return writeFile(context.Background(), path, s, gzip.DefaultCompression)
+}
+
+// ToFileContext is like ToFile but stops writing with the error of ctx once
+// it is done.
+func (s *SitemapIndex) ToFileContext(ctx context.Context, path string) error {
+	return writeFile(ctx, path, s, gzip.DefaultCompression)
+}
+
+// ToFileWithLevel is like ToFile but gzips the file with the given
+// compression level, which is one of the compress/gzip levels. Any other
+// level fails, also for a file that is not gzipped.
+func (s *SitemapIndex) ToFileWithLevel(path string, level int) error {
+	return writeFile(context.Background(), path, s, level)
+}
+
+// WriteToFS saves the sitemap index to the file name in fsys like ToFile does to
+// the OS filesystem
+func (s *SitemapIndex) WriteToFS(fsys WriteFS, name string) error {
+	return writeFS(context.Background(), fsys, name, s, gzip.DefaultCompression)
+}
+
+// NewIndexFromDir creates a sitemap index by scanning a folder for files.
+// The files modified time will be used as LastMod. Files with "index" in
+// their name are skipped, see SkipIndexFiles.
+func NewIndexFromDir(dir, pathPrefix, filenamePrefix string) (*SitemapIndex, error) {
+	return NewIndexFromDirContext(context.Background(), dir, pathPrefix, filenamePrefix)
+}
+
+// NewIndexFromDirContext is like NewIndexFromDir but stops scanning the
+// folder with the error of ctx once it is done.
+func NewIndexFromDirContext(ctx context.Context, dir, pathPrefix, filenamePrefix string) (*SitemapIndex, error) {
+	return NewIndexFromDirOptions(ctx, dir, DirOptions{
+		PathPrefix:     pathPrefix,
+		FilenamePrefix: filenamePrefix,
+	})
+}
+
+// DirOptions configures how NewIndexFromDirOptions scans a folder
+type DirOptions struct {
+	// PathPrefix is the URL that the path of a file, relative to the
+	// folder, is joined to get its Loc. When empty, the Loc is just the
+	// relative path.
+	PathPrefix string
+
+	// BaseURL is the absolute URL that a Loc is resolved against when
+	// PathPrefix is relative or empty, see SitemapIndex.SetBaseURL
+	BaseURL string
+
+	// FilenamePrefix limits the scan to files with names starting with it
+	FilenamePrefix string
+
+	// Recursive makes the scan descend into subfolders. Symbolic links are
+	// not followed, so a link back up the tree can not make it loop.
+	Recursive bool
+
+	// Skip reports whether a file should be left out of the index. It gets
+	// the path of the file relative to the folder, with forward slashes.
+	// When nil, SkipIndexFiles is used so an existing index in the folder
+	// does not end up referencing itself.
+	Skip func(name string) bool
+
+	// Verify makes the scan read every file, decompressing gzipped ones,
+	// and fail on a file that is not a urlset, so that the index does not
+	// reference a sitemap that was not written correctly
+	Verify bool
+
+	// SkipInvalid leaves a file that fails verification out of the index
+	// instead of failing the scan
+	SkipInvalid bool
+
+	// Progress is called after each file that is added to the index or
+	// left out, with the number of files processed so far out of the total
+	// number of matching files, so that tools can show the progress of a
+	// large folder
+	Progress func(processed, total int)
+}
+
+// SkipIndexFiles reports whether the file name contains "index", which is
+// how sitemap index files are usually named
+func SkipIndexFiles(name string) bool {
+	return strings.Contains(strings.ToLower(path.Base(name)), "index")
+}
+
+// NewIndexFromDirOptions creates a sitemap index by scanning a folder for
+// .xml and .gz files as configured by opts. The files modified time will be
+// used as LastMod. Scanning stops with the error of ctx once it is done.
+func NewIndexFromDirOptions(ctx context.Context, dir string, opts DirOptions) (*SitemapIndex, error) {
+	s := NewSitemapIndex()
+	if err := s.SetBaseURL(opts.BaseURL); err != nil {
+		return s, err
+	}
+
+	// The folder itself may be a symbolic link
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return s, err
+	}
+
+	// Find the files first so that the progress has a total
+	type match struct {
+		path, rel string
+		file      fs.DirEntry
+	}
+	var matches []match
+
+	err = filepath.WalkDir(root, func(p string, file fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if file.IsDir() {
+			if p != root && !opts.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(file.Name())
+		if !file.Type().IsRegular() || !strings.HasPrefix(file.Name(), opts.FilenamePrefix) || (ext != ".xml" && ext != ".gz") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		skip := opts.Skip
+		if skip == nil {
+			skip = SkipIndexFiles
+		}
+		if skip(rel) {
+			return nil
+		}
+
+		matches = append(matches, match{p, rel, file})
+		return nil
+	})
+	if err != nil {
+		return s, err
+	}
+
+	for i, m := range matches {
+		if err := ctx.Err(); err != nil {
+			return s, err
+		}
+
+		if err := s.addDirFile(m.path, m.rel, m.file, opts); err != nil {
+			return s, err
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(matches))
+		}
+	}
+
+	return s, nil
+}
+
+// addDirFile adds the file found by NewIndexFromDirOptions at path, which
+// is rel relative to the folder, to the index
+func (s *SitemapIndex) addDirFile(path, rel string, file fs.DirEntry, opts DirOptions) error {
+	if opts.Verify {
+		if err := verifySitemapFile(path); err != nil {
+			if opts.SkipInvalid {
+				return nil
+			}
+			return fmt.Errorf("invalid sitemap %s: %w", rel, err)
+		}
+	}
+
+	info, err := file.Info()
+	if err != nil {
+		return err
+	}
+
+	loc, err := joinURL(opts.PathPrefix, rel)
+	if err != nil {
+		return err
+	}
+	s.Add(SitemapIndexItem{
+		loc,
+		info.ModTime(),
+	})
+
+	return nil
+}
+
+// MergeDir combines the items of the sitemaps in a folder, the .xml and .gz
+// files that are not skipped by SkipIndexFiles, into one sitemap. An item
+// that is in several sitemaps is added once. The files are read in the
+// order of their names. It fails like Sitemap.Add once the sitemap has
+// MaxSitemapItems items, Split divides a sitemap that has to hold more.
+func MergeDir(dir string) (*Sitemap, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New()
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if !file.Type().IsRegular() || (ext != ".xml" && ext != ".gz") || SkipIndexFiles(file.Name()) {
+			continue
+		}
+
+		if err := s.mergeFile(filepath.Join(dir, file.Name())); err != nil {
+			return nil, fmt.Errorf("could not merge %s: %w", file.Name(), err)
+		}
+	}
+
+	return s, nil
+}
+
+// mergeFile adds the items of the sitemap file at path that are not in the
+// sitemap yet
+func (s *Sitemap) mergeFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ParseStream(file, func(item SitemapItem) error {
+		_, err := s.AddUnique(item)
+		return err
+	})
+}
+
+// verifySitemapFile checks that the file at path, which may be gzipped, is
+// a complete urlset. The items are not checked.
+func verifySitemapFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ParseStream(file, func(SitemapItem) error { return nil })
+}
+
+// NewIndex creates a sitemap index of sitemaps kept in memory, keyed by their
+// filename. The loc of each sitemap is its filename joined to pathPrefix and
+// its lastmod is the newest LastMod of its items. The sitemaps are sorted by
+// filename.
+func NewIndex(pathPrefix string, sitemaps map[string]*Sitemap) (*SitemapIndex, error) {
+	s := NewSitemapIndex()
+
+	names := make([]string, 0, len(sitemaps))
+	for name := range sitemaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		loc, err := joinURL(pathPrefix, name)
+		if err != nil {
+			return s, err
+		}
+		s.AddSitemap(loc, sitemaps[name])
+	}
+
+	return s, nil
+}
+
+// joinURL joins the slash separated path name to the URL prefix with a
+// single slash between them, escaping name as needed. An empty prefix
+// returns name as a relative URL.
+func joinURL(prefix, name string) (string, error) {
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return "", fmt.Errorf("path prefix %q is not a valid URL: %v", prefix, err)
+	}
+
+	if prefix == "" {
+		return (&url.URL{Path: name}).String(), nil
+	}
+
+	return u.JoinPath(name).String(), nil
+}
+
+// countWriter counts the bytes written to w and keeps the first error, so
+// that a sequence of writes only has to be checked once at the end
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// WriteString writes str to the underlying writer unless an earlier write
+// has failed
+func (c *countWriter) WriteString(str string) {
+	if c.err != nil {
+		return
+	}
+
+	n, err := io.WriteString(c.w, str)
+	c.n += int64(n)
+	c.err = err
+}
+
+// Write is like WriteString, so that countWriter can be used as an io.Writer
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+
+	return n, err
+}
+
+// WriteChunked writes items to dir as sitemap-1.xml, sitemap-2.xml and so on,
+// with as many items per file as fit within MaxSitemapItems and the maximum
+// size, and returns a sitemap index of the written files. If compress is
+// true, the files are gzipped and get the extension .xml.gz instead. The
+// locations in the index are the filenames joined to pathPrefix, and their
+// LastMod is the time of writing. No file is written if an item can not be
+// added.
+func WriteChunked(items []SitemapItem, dir, pathPrefix string, compress bool) (*SitemapIndex, error) {
+	return WriteChunkedOptions(items, dir, ChunkOptions{
+		PathPrefix: pathPrefix,
+		Compress:   compress,
+	})
+}
+
+// WriteChunkedHashed is like WriteChunked but adds the content hash of each
+// sitemap to its filename like ToHashedFile does, so sitemap-1.xml becomes
+// sitemap-1-0123abcd.xml. The index references the hashed filenames.
+func WriteChunkedHashed(items []SitemapItem, dir, pathPrefix string, compress bool) (*SitemapIndex, error) {
+	return WriteChunkedOptions(items, dir, ChunkOptions{
+		PathPrefix: pathPrefix,
+		Compress:   compress,
+		Hashed:     true,
+	})
+}
+
+// ChunkOptions configures how WriteChunkedOptions writes items
+type ChunkOptions struct {
+	// PathPrefix is the URL that the filenames are joined to for the
+	// locations in the index
+	PathPrefix string
+
+	// Compress gzips the files, which get the extension .xml.gz
+	Compress bool
+
+	// Hashed adds the content hash of each sitemap to its filename, see
+	// WriteChunkedHashed
+	Hashed bool
+
+	// MaxItems lowers the maximum number of items per file, see
+	// Sitemap.SetMaxItems
+	MaxItems int
+}
+
+// WriteChunkedOptions writes items to dir like WriteChunked, as configured by
+// opts
+func WriteChunkedOptions(items []SitemapItem, dir string, opts ChunkOptions) (*SitemapIndex, error) {
+	index := NewSitemapIndex()
+
+	chunks, err := chunk(items, opts.MaxItems)
+	if err != nil {
+		return index, err
+	}
+
+	ext := ".xml"
+	if opts.Compress {
+		ext = ".xml.gz"
+	}
+
+	for i, s := range chunks {
+		filename := fmt.Sprintf("sitemap-%d%s", i+1, ext)
+		if opts.Hashed {
+			filename = hashedName(filename, s.hash())
+		}
+		if err := s.ToFile(filepath.Join(dir, filename)); err != nil {
+			return index, err
+		}
+
+		loc, err := joinURL(opts.PathPrefix, filename)
+		if err != nil {
+			return index, err
+		}
+		index.Add(SitemapIndexItem{
+			loc,
+			Now(),
+		})
+	}
+
+	return index, nil
+}
+
+// chunk adds items to as few sitemaps of at most maxItems items as they fit
+// in, starting a new one whenever the current one has reached the maximum
+// number of items or the maximum size
+func chunk(items []SitemapItem, maxItems int) ([]*Sitemap, error) {
+	var chunks []*Sitemap
+	s := New()
+	s.SetMaxItems(maxItems)
+	for i, item := range items {
+		err := s.Add(item)
+		if s.Len() > 0 && (errors.Is(err, ErrMaxItemsExceeded) || errors.Is(err, ErrMaxSizeExceeded)) {
+			chunks = append(chunks, s)
+			s = New()
+			s.SetMaxItems(maxItems)
+			err = s.Add(item)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	if s.Len() > 0 {
+		chunks = append(chunks, s)
+	}
+
+	return chunks, nil
 }
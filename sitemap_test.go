@@ -1,11 +1,24 @@
 package sitemap
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"math"
 	"os"
 	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -49,10 +62,10 @@ func TestFileGeneration(t *testing.T) {
 
 	// Sitemap item
 	item := SitemapItem{
-		"http://www.google.com",
-		lastMod,
-		"hourly",
-		0.5,
+		Loc:        "http://www.google.com",
+		LastMod:    lastMod,
+		ChangeFreq: "hourly",
+		Priority:   Priority(0.5),
 	}
 
 	if item.String() != itemResult {
@@ -61,7 +74,7 @@ func TestFileGeneration(t *testing.T) {
 
 	// Sitemap
 	sitemap := Sitemap{
-		[]SitemapItem{
+		items: []SitemapItem{
 			item,
 		},
 	}
@@ -88,7 +101,7 @@ func TestFileGeneration(t *testing.T) {
 
 	// SitemapIndex
 	sitemapIndex := SitemapIndex{
-		[]SitemapIndexItem{
+		items: []SitemapIndexItem{
 			sitemapIndexItem,
 		},
 	}
@@ -97,7 +110,7 @@ func TestFileGeneration(t *testing.T) {
 		t.Errorf("Expected sitemap index to be %s, actual: %s", sitemapIndexResult, sitemapIndex.String())
 	}
 
-	sitemapIndex2, err := NewIndexFromDir(testDir, "http://www.google.com/")
+	sitemapIndex2, err := NewIndexFromDir(testDir, "http://www.google.com/", "")
 	if err != nil {
 		log.Fatalf("could not create sitemap index from directory: %v", err)
 	}
@@ -132,3 +145,1897 @@ func TestFileGeneration(t *testing.T) {
 	}
 
 }
+
+func TestEscaping(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	tests := map[string]string{
+		"http://www.example.com/search?a=1&b=2&c=3":     "http://www.example.com/search?a=1&amp;b=2&amp;c=3",
+		"http://www.example.com/?q=\"<tag>\"&sort='a'":  "http://www.example.com/?q=&#34;&lt;tag&gt;&#34;&amp;sort=&#39;a&#39;",
+		"http://www.example.com/städte/münchen?ü=1&ö=2": "http://www.example.com/städte/münchen?ü=1&amp;ö=2",
+	}
+
+	for loc, escaped := range tests {
+		item := SitemapItem{Loc: loc, LastMod: lastMod, ChangeFreq: "hourly", Priority: Priority(0.5)}
+		expected := fmt.Sprintf(`
+	<url>
+		<loc>%s</loc>
+		<lastmod>2014-03-31T15:00:00+01:00</lastmod>
+		<changefreq>hourly</changefreq>
+		<priority>0.5</priority>
+	</url>`, escaped)
+		if item.String() != expected {
+			t.Errorf("Expected sitemap item to be %s, actual: %s", expected, item.String())
+		}
+
+		indexItem := SitemapIndexItem{loc, lastMod}
+		expected = fmt.Sprintf(`
+	<sitemap>
+		<loc>%s</loc>
+		<lastmod>2014-03-31T15:00:00+01:00</lastmod>
+	</sitemap>`, escaped)
+		if indexItem.String() != expected {
+			t.Errorf("Expected sitemap index item to be %s, actual: %s", expected, indexItem.String())
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	sitemap := Sitemap{
+		items: []SitemapItem{
+			{Loc: "http://www.google.com", LastMod: lastMod, ChangeFreq: "hourly", Priority: Priority(0.5)},
+			{Loc: "http://www.google.com/about", LastMod: lastMod, ChangeFreq: "daily", Priority: Priority(0.8)},
+		},
+	}
+
+	var buf bytes.Buffer
+	n, err := sitemap.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("could not write sitemap: %v", err)
+	}
+	if buf.String() != sitemap.String() {
+		t.Errorf("Expected sitemap written to be %s, actual: %s", sitemap.String(), buf.String())
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected number of bytes written to be %d, actual: %d", buf.Len(), n)
+	}
+
+	sitemapIndex := SitemapIndex{
+		items: []SitemapIndexItem{
+			{"http://www.google.com/sitemap-1.xml.gz", lastMod},
+			{"http://www.google.com/sitemap-2.xml.gz", lastMod},
+		},
+	}
+
+	buf.Reset()
+	n, err = sitemapIndex.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("could not write sitemap index: %v", err)
+	}
+	if buf.String() != sitemapIndex.String() {
+		t.Errorf("Expected sitemap index written to be %s, actual: %s", sitemapIndex.String(), buf.String())
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected number of bytes written to be %d, actual: %d", buf.Len(), n)
+	}
+}
+
+func TestAddPriority(t *testing.T) {
+	tests := map[float32]bool{
+		0:                   true,
+		0.5:                 true,
+		1:                   true,
+		-1:                  false,
+		1.01:                false,
+		5:                   false,
+		float32(math.NaN()): false,
+	}
+
+	for priority, valid := range tests {
+		sitemap := Sitemap{}
+		err := sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: time.Now(), ChangeFreq: "hourly", Priority: Priority(priority)})
+		if valid && err != nil {
+			t.Errorf("Expected priority %.2f to be accepted, got error: %v", priority, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected priority %.2f to be rejected", priority)
+		}
+	}
+
+	nan := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>http://www.google.com</loc><priority>NaN</priority></url></urlset>`
+	if _, err := Parse(strings.NewReader(nan)); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("Expected parsing priority NaN to fail with %v, actual: %v", ErrInvalidPriority, err)
+	}
+}
+
+func TestAddChangeFreq(t *testing.T) {
+	tests := map[string]bool{
+		"":                true,
+		ChangeFreqAlways:  true,
+		ChangeFreqHourly:  true,
+		ChangeFreqDaily:   true,
+		ChangeFreqWeekly:  true,
+		ChangeFreqMonthly: true,
+		ChangeFreqYearly:  true,
+		ChangeFreqNever:   true,
+		"dialy":           false,
+		"Daily":           false,
+	}
+
+	for changeFreq, valid := range tests {
+		sitemap := Sitemap{}
+		err := sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: time.Now(), ChangeFreq: changeFreq, Priority: Priority(0.5)})
+		if valid && err != nil {
+			t.Errorf("Expected changefreq %q to be accepted, got error: %v", changeFreq, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected changefreq %q to be rejected", changeFreq)
+		}
+	}
+}
+
+func TestOptionalElements(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	tests := []struct {
+		item     SitemapItem
+		expected string
+	}{
+		{
+			SitemapItem{Loc: "http://www.google.com"},
+			`
+	<url>
+		<loc>http://www.google.com</loc>
+	</url>`,
+		},
+		{
+			SitemapItem{Loc: "http://www.google.com", LastMod: lastMod},
+			`
+	<url>
+		<loc>http://www.google.com</loc>
+		<lastmod>2014-03-31T15:00:00+01:00</lastmod>
+	</url>`,
+		},
+		{
+			SitemapItem{Loc: "http://www.google.com", ChangeFreq: ChangeFreqDaily},
+			`
+	<url>
+		<loc>http://www.google.com</loc>
+		<changefreq>daily</changefreq>
+	</url>`,
+		},
+		{
+			SitemapItem{Loc: "http://www.google.com", Priority: Priority(0)},
+			`
+	<url>
+		<loc>http://www.google.com</loc>
+		<priority>0.0</priority>
+	</url>`,
+		},
+	}
+
+	for _, test := range tests {
+		if test.item.String() != test.expected {
+			t.Errorf("Expected sitemap item to be %s, actual: %s", test.expected, test.item.String())
+		}
+	}
+}
+
+func TestWriteChunked(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	items := make([]SitemapItem, 3)
+	for i := range items {
+		items[i] = SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)}
+	}
+
+	index, err := WriteChunkedOptions(items, dir, ChunkOptions{
+		PathPrefix: "http://www.google.com/",
+		Compress:   true,
+		MaxItems:   2,
+	})
+	if err != nil {
+		t.Fatalf("could not write chunked sitemaps: %v", err)
+	}
+
+	expected := []string{
+		"http://www.google.com/sitemap-1.xml.gz",
+		"http://www.google.com/sitemap-2.xml.gz",
+	}
+	if len(index.items) != len(expected) {
+		t.Fatalf("Expected sitemap index to have %d items, actual: %d", len(expected), len(index.items))
+	}
+	for i, loc := range expected {
+		if index.items[i].Loc != loc {
+			t.Errorf("Expected sitemap index item %d to be %s, actual: %s", i, loc, index.items[i].Loc)
+		}
+		if !index.items[i].LastMod.Equal(now) {
+			t.Errorf("Expected lastmod of sitemap index item %d to be %v, actual: %v", i, now, index.items[i].LastMod)
+		}
+		if _, err := os.Stat(path.Join(dir, path.Base(loc))); err != nil {
+			t.Errorf("Expected sitemap file %s to exist: %v", path.Base(loc), err)
+		}
+	}
+}
+
+func TestWriteChunkedBySize(t *testing.T) {
+	dir := t.TempDir()
+
+	padding := strings.Repeat("a", 2000)
+	items := make([]SitemapItem, 30000)
+	for i := range items {
+		items[i] = SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d/%s", i, padding)}
+	}
+
+	index, err := WriteChunked(items, dir, "http://www.google.com/", true)
+	if err != nil {
+		t.Fatalf("could not write chunked sitemaps: %v", err)
+	}
+	if index.Len() != 2 {
+		t.Errorf("Expected items over the maximum size to be split into %d sitemaps, actual: %d", 2, index.Len())
+	}
+
+	dir = t.TempDir()
+	items[len(items)-1].Loc = "/relative"
+	if _, err := WriteChunked(items, dir, "http://www.google.com/", true); !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected invalid item to fail, actual: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files to be written when an item is invalid, actual: %d", len(entries))
+	}
+}
+
+func TestAddMaxBytes(t *testing.T) {
+	sitemap := Sitemap{}
+
+	loc := "http://www.google.com/" + strings.Repeat("a", 2000)
+	var err error
+	for i := 0; err == nil; i++ {
+		if i == MaxSitemapItems {
+			t.Fatalf("Expected items exceeding %d bytes to be rejected", MaxUncompressedBytes)
+		}
+		err = sitemap.Add(SitemapItem{Loc: fmt.Sprintf("%s/%d", loc, i)})
+	}
+
+	if len(sitemap.String()) > MaxUncompressedBytes {
+		t.Errorf("Expected sitemap to be at most %d bytes, actual: %d", MaxUncompressedBytes, len(sitemap.String()))
+	}
+	if sitemap.size+len(sitemapHeader)+len(sitemapFooter) != len(sitemap.String()) {
+		t.Errorf("Expected tracked size to be %d, actual: %d", len(sitemap.String()), sitemap.size+len(sitemapHeader)+len(sitemapFooter))
+	}
+}
+
+func TestAddLoc(t *testing.T) {
+	tests := map[string]bool{
+		"http://www.google.com":                              true,
+		"https://www.google.com/search?q=sitemap":            true,
+		"http://www.google.com/" + strings.Repeat("a", 2026): true,
+		"http://www.google.com/" + strings.Repeat("a", 2027): false,
+		"/relative/path":                                     false,
+		"www.google.com":                                     false,
+		"not a url":                                          false,
+		"http://":                                            false,
+		"http://www.google.com/%zz":                          false,
+	}
+
+	for loc, valid := range tests {
+		sitemap := Sitemap{}
+		err := sitemap.Add(SitemapItem{Loc: loc})
+		if valid && err != nil {
+			t.Errorf("Expected loc %q to be accepted, got error: %v", loc, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected loc %q to be rejected", loc)
+		}
+	}
+}
+
+func TestToFileWithLevel(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	for _, level := range []int{gzip.HuffmanOnly, gzip.DefaultCompression, gzip.NoCompression, gzip.BestSpeed, gzip.BestCompression} {
+		if err := sitemap.ToFileWithLevel(path.Join(dir, "sitemap.xml.gz"), level); err != nil {
+			t.Errorf("Expected gzip level %d to be accepted, got error: %v", level, err)
+		}
+	}
+
+	for _, level := range []int{-3, 10} {
+		if err := sitemap.ToFileWithLevel(path.Join(dir, "sitemap.xml.gz"), level); err == nil {
+			t.Errorf("Expected gzip level %d to be rejected", level)
+		}
+	}
+
+	if err := sitemap.ToFileWithLevel(path.Join(dir, "sitemap.xml"), 42); err == nil {
+		t.Errorf("Expected gzip level %d to be rejected for a file that is not gzipped", 42)
+	}
+	if _, err := os.Stat(path.Join(dir, "sitemap.xml")); err == nil {
+		t.Errorf("Expected no file for the rejected gzip level")
+	}
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	sitemap := Sitemap{}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 10; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < MaxSitemapItems/10+10; i++ {
+				sitemap.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d/%d", worker, i)})
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	if len(sitemap.items) != MaxSitemapItems {
+		t.Errorf("Expected sitemap to have %d items, actual: %d", MaxSitemapItems, len(sitemap.items))
+	}
+}
+
+func TestConcurrentMerge(t *testing.T) {
+	other := New()
+	for i := 0; i < 100; i++ {
+		other.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", 100-i)})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			other.Sort()
+			other.SortByLastMod()
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := New().Merge(other); err != nil {
+			t.Errorf("could not merge sitemaps: %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestLen(t *testing.T) {
+	sitemap := Sitemap{}
+	sitemapIndex := SitemapIndex{}
+
+	for i := 0; i < 3; i++ {
+		if sitemap.Len() != i {
+			t.Errorf("Expected sitemap length to be %d, actual: %d", i, sitemap.Len())
+		}
+		if sitemapIndex.Len() != i {
+			t.Errorf("Expected sitemap index length to be %d, actual: %d", i, sitemapIndex.Len())
+		}
+
+		sitemap.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)})
+		sitemapIndex.Add(SitemapIndexItem{Loc: fmt.Sprintf("http://www.google.com/sitemap-%d.xml", i)})
+	}
+}
+
+func TestAddUnique(t *testing.T) {
+	sitemap := Sitemap{}
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a"})
+
+	tests := []struct {
+		loc   string
+		added bool
+	}{
+		{"http://www.google.com/a", false},
+		{"http://www.google.com/b", true},
+		{"http://www.google.com/b", false},
+		{"http://www.google.com/c", true},
+	}
+
+	for _, test := range tests {
+		added, err := sitemap.AddUnique(SitemapItem{Loc: test.loc})
+		if err != nil {
+			t.Fatalf("could not add %s: %v", test.loc, err)
+		}
+		if added != test.added {
+			t.Errorf("Expected adding %s to report %v, actual: %v", test.loc, test.added, added)
+		}
+	}
+
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/d"})
+	if added, _ := sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/d"}); added {
+		t.Errorf("Expected item added with Add to be known to AddUnique")
+	}
+
+	if sitemap.Len() != 4 {
+		t.Errorf("Expected sitemap to have %d items, actual: %d", 4, sitemap.Len())
+	}
+}
+
+func TestSetBaseURL(t *testing.T) {
+	sitemap := Sitemap{}
+	if err := sitemap.SetBaseURL("http://www.google.com/shop/"); err != nil {
+		t.Fatalf("could not set base URL: %v", err)
+	}
+
+	tests := map[string]string{
+		"/products/42":               "http://www.google.com/products/42",
+		"products/43?color=red":      "http://www.google.com/shop/products/43?color=red",
+		"https://www.example.com/a/": "https://www.example.com/a/",
+		"https://www.example.com/./": "https://www.example.com/./",
+	}
+
+	for loc, expected := range tests {
+		sitemap.Add(SitemapItem{Loc: loc})
+		if actual := sitemap.items[len(sitemap.items)-1].Loc; actual != expected {
+			t.Errorf("Expected loc %s to be resolved to %s, actual: %s", loc, expected, actual)
+		}
+	}
+
+	if added, _ := sitemap.AddUnique(SitemapItem{Loc: "/products/42"}); added {
+		t.Errorf("Expected relative loc to be deduplicated after resolving")
+	}
+
+	for _, baseURL := range []string{"/relative", "not a url", "http://%zz"} {
+		if err := sitemap.SetBaseURL(baseURL); err == nil {
+			t.Errorf("Expected base URL %q to be rejected", baseURL)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	sitemap := New()
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com"}); err != nil {
+		t.Errorf("could not add item to new sitemap: %v", err)
+	}
+	if sitemap.Len() != 1 {
+		t.Errorf("Expected new sitemap to have %d items, actual: %d", 1, sitemap.Len())
+	}
+
+	var zero Sitemap
+	if New().String() != zero.String() {
+		t.Errorf("Expected new sitemap to be %s, actual: %s", zero.String(), New().String())
+	}
+
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	if sitemapIndex.Len() != 1 {
+		t.Errorf("Expected new sitemap index to have %d items, actual: %d", 1, sitemapIndex.Len())
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := sitemap.ToFileContext(ctx, path.Join(dir, "sitemap.xml")); err != nil {
+		t.Fatalf("could not save the sitemap to a file: %v", err)
+	}
+	if _, err := NewIndexFromDirContext(ctx, dir, "http://www.google.com/", ""); err != nil {
+		t.Fatalf("could not create sitemap index from directory: %v", err)
+	}
+
+	cancel()
+	for _, name := range []string{"sitemap-2.xml", "sitemap-2.xml.gz"} {
+		if err := sitemap.ToFileContext(ctx, path.Join(dir, name)); err != context.Canceled {
+			t.Errorf("Expected saving %s with a canceled context to fail with %v, actual: %v", name, context.Canceled, err)
+		}
+	}
+	if _, err := NewIndexFromDirContext(ctx, dir, "http://www.google.com/", ""); err != context.Canceled {
+		t.Errorf("Expected scanning with a canceled context to fail with %v, actual: %v", context.Canceled, err)
+	}
+}
+
+func TestNewIndexFromDirRecursive(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	for _, name := range []string{"sitemap.xml", "2023/sitemap.xml", "2024/sitemap.xml.gz", "2024/01/sitemap.xml"} {
+		os.MkdirAll(path.Dir(path.Join(dir, name)), 0755)
+		if err := sitemap.ToFile(path.Join(dir, name)); err != nil {
+			t.Fatalf("could not save sitemap %s: %v", name, err)
+		}
+	}
+	if err := os.Symlink(dir, path.Join(dir, "2024", "loop")); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+
+	tests := map[bool][]string{
+		false: {"http://www.google.com/sitemap.xml"},
+		true: {
+			"http://www.google.com/2023/sitemap.xml",
+			"http://www.google.com/2024/01/sitemap.xml",
+			"http://www.google.com/2024/sitemap.xml.gz",
+			"http://www.google.com/sitemap.xml",
+		},
+	}
+
+	for recursive, expected := range tests {
+		index, err := NewIndexFromDirOptions(context.Background(), dir, DirOptions{
+			PathPrefix: "http://www.google.com/",
+			Recursive:  recursive,
+		})
+		if err != nil {
+			t.Fatalf("could not create sitemap index from directory: %v", err)
+		}
+
+		var locs []string
+		for _, item := range index.items {
+			locs = append(locs, item.Loc)
+		}
+		if !reflect.DeepEqual(locs, expected) {
+			t.Errorf("Expected sitemap index with recursive %v to have %v, actual: %v", recursive, expected, locs)
+		}
+	}
+}
+
+func TestNewIndexFromDirSkip(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	for _, name := range []string{"sitemap-1.xml", "sitemap-2.xml", "sitemap-index.xml", "Sitemap_Index.xml.gz"} {
+		if err := sitemap.ToFile(path.Join(dir, name)); err != nil {
+			t.Fatalf("could not save sitemap %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		skip     func(string) bool
+		expected []string
+	}{
+		{
+			nil,
+			[]string{"http://www.google.com/sitemap-1.xml", "http://www.google.com/sitemap-2.xml"},
+		},
+		{
+			func(name string) bool { return name == "sitemap-2.xml" },
+			[]string{"http://www.google.com/Sitemap_Index.xml.gz", "http://www.google.com/sitemap-1.xml", "http://www.google.com/sitemap-index.xml"},
+		},
+	}
+
+	for _, test := range tests {
+		index, err := NewIndexFromDirOptions(context.Background(), dir, DirOptions{
+			PathPrefix: "http://www.google.com/",
+			Skip:       test.skip,
+		})
+		if err != nil {
+			t.Fatalf("could not create sitemap index from directory: %v", err)
+		}
+
+		var locs []string
+		for _, item := range index.items {
+			locs = append(locs, item.Loc)
+		}
+		if !reflect.DeepEqual(locs, test.expected) {
+			t.Errorf("Expected sitemap index to have %v, actual: %v", test.expected, locs)
+		}
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		prefix, name, expected string
+	}{
+		{"http://www.google.com/maps", "sitemap.xml", "http://www.google.com/maps/sitemap.xml"},
+		{"http://www.google.com/maps/", "sitemap.xml", "http://www.google.com/maps/sitemap.xml"},
+		{"http://www.google.com/maps//", "sitemap.xml", "http://www.google.com/maps/sitemap.xml"},
+		{"http://www.google.com", "2024/site map.xml", "http://www.google.com/2024/site%20map.xml"},
+		{"", "2024/sitemap.xml", "2024/sitemap.xml"},
+	}
+
+	for _, test := range tests {
+		actual, err := joinURL(test.prefix, test.name)
+		if err != nil {
+			t.Errorf("could not join %s and %s: %v", test.prefix, test.name, err)
+		}
+		if actual != test.expected {
+			t.Errorf("Expected %s joined with %s to be %s, actual: %s", test.prefix, test.name, test.expected, actual)
+		}
+	}
+
+	if _, err := joinURL("http://%zz", "sitemap.xml"); err == nil {
+		t.Errorf("Expected invalid path prefix to be rejected")
+	}
+}
+
+func TestSort(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b", LastMod: lastMod})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/c", LastMod: lastMod.Add(time.Hour)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a", LastMod: lastMod})
+
+	locs := func() []string {
+		var locs []string
+		for _, item := range sitemap.items {
+			locs = append(locs, item.Loc)
+		}
+		return locs
+	}
+
+	sitemap.Sort()
+	expected := []string{"http://www.google.com/a", "http://www.google.com/b", "http://www.google.com/c"}
+	if !reflect.DeepEqual(locs(), expected) {
+		t.Errorf("Expected items sorted by loc to be %v, actual: %v", expected, locs())
+	}
+
+	sitemap.SortByLastMod()
+	expected = []string{"http://www.google.com/c", "http://www.google.com/a", "http://www.google.com/b"}
+	if !reflect.DeepEqual(locs(), expected) {
+		t.Errorf("Expected items sorted by lastmod to be %v, actual: %v", expected, locs())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	sitemap := New()
+	other := New()
+	for i := 0; i < MaxSitemapItems/2; i++ {
+		sitemap.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/a/%d", i)})
+		other.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/b/%d", i)})
+	}
+
+	if err := sitemap.Merge(other); err != nil {
+		t.Fatalf("could not merge sitemaps: %v", err)
+	}
+	if sitemap.Len() != MaxSitemapItems {
+		t.Errorf("Expected merged sitemap to have %d items, actual: %d", MaxSitemapItems, sitemap.Len())
+	}
+	if sitemap.size+len(sitemapHeader)+len(sitemapFooter) != len(sitemap.String()) {
+		t.Errorf("Expected tracked size to be %d, actual: %d", len(sitemap.String()), sitemap.size+len(sitemapHeader)+len(sitemapFooter))
+	}
+
+	if err := sitemap.Merge(other); err == nil {
+		t.Errorf("Expected merging beyond %d items to fail", MaxSitemapItems)
+	}
+	if sitemap.Len() != MaxSitemapItems {
+		t.Errorf("Expected failed merge to leave %d items, actual: %d", MaxSitemapItems, sitemap.Len())
+	}
+
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap-1.xml"})
+	otherIndex := NewSitemapIndex()
+	otherIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap-2.xml"})
+	if err := sitemapIndex.Merge(otherIndex); err != nil {
+		t.Fatalf("could not merge sitemap indexes: %v", err)
+	}
+	if sitemapIndex.Len() != 2 {
+		t.Errorf("Expected merged sitemap index to have %d items, actual: %d", 2, sitemapIndex.Len())
+	}
+
+	for i := 0; i < MaxSitemapIndexItems-1; i++ {
+		otherIndex.Add(SitemapIndexItem{Loc: fmt.Sprintf("http://www.google.com/sitemap-%d.xml", i+3)})
+	}
+	if err := sitemapIndex.Merge(otherIndex); !errors.Is(err, ErrMaxItemsExceeded) {
+		t.Errorf("Expected merging beyond %d sitemaps to fail with %v, actual: %v", MaxSitemapIndexItems, ErrMaxItemsExceeded, err)
+	}
+	if sitemapIndex.Len() != 2 {
+		t.Errorf("Expected failed merge to leave %d items, actual: %d", 2, sitemapIndex.Len())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	if err := sitemap.Validate(); err != nil {
+		t.Errorf("Expected sitemap to be valid, got error: %v", err)
+	}
+
+	sitemap.items = append(sitemap.items,
+		SitemapItem{Loc: "/relative"},
+		SitemapItem{Loc: "http://www.google.com/a", Priority: Priority(2)},
+		SitemapItem{Loc: "http://www.google.com/b", ChangeFreq: "dialy"},
+	)
+	err := sitemap.Validate()
+	if err == nil {
+		t.Fatalf("Expected sitemap with invalid items to be invalid")
+	}
+	for _, problem := range []string{"item 1:", "item 2:", "item 3:"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected error to report %q, actual: %v", problem, err)
+		}
+	}
+
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	if err := sitemapIndex.Validate(); err != nil {
+		t.Errorf("Expected sitemap index to be valid, got error: %v", err)
+	}
+	sitemapIndex.Add(SitemapIndexItem{Loc: "sitemap.xml"})
+	if err := sitemapIndex.Validate(); err == nil {
+		t.Errorf("Expected sitemap index with relative loc to be invalid")
+	}
+}
+
+func TestSetLastModFormat(t *testing.T) {
+	lastMod, _ := time.Parse(time.RFC3339, "2014-03-31T15:00:00+01:00")
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: lastMod})
+
+	for layout, expected := range map[string]string{
+		"":                       "<lastmod>2014-03-31T15:00:00+01:00</lastmod>",
+		time.DateOnly:            "<lastmod>2014-03-31</lastmod>",
+		"2006-01-02T15:04Z07:00": "<lastmod>2014-03-31T15:00+01:00</lastmod>",
+	} {
+		sitemap.SetLastModFormat(layout)
+		if !strings.Contains(sitemap.String(), expected) {
+			t.Errorf("Expected sitemap with lastmod format %q to contain %s, actual: %s", layout, expected, sitemap.String())
+		}
+	}
+}
+
+func TestSetLastModPrecision(t *testing.T) {
+	lastMod := time.Date(2014, 3, 31, 15, 0, 0, 123456789, time.UTC)
+
+	sitemap := New()
+	sitemap.SetLastModFormat(time.RFC3339Nano)
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: lastMod})
+
+	if expected := "<lastmod>2014-03-31T15:00:00Z</lastmod>"; !strings.Contains(sitemap.String(), expected) {
+		t.Errorf("Expected sitemap to contain %s, actual: %s", expected, sitemap.String())
+	}
+
+	for precision, expected := range map[time.Duration]string{
+		0:                "<lastmod>2014-03-31T15:00:00.123456789Z</lastmod>",
+		time.Millisecond: "<lastmod>2014-03-31T15:00:00.123Z</lastmod>",
+		time.Second:      "<lastmod>2014-03-31T15:00:00Z</lastmod>",
+	} {
+		sitemap.SetLastModPrecision(precision)
+		if !strings.Contains(sitemap.String(), expected) {
+			t.Errorf("Expected sitemap with lastmod precision %v to contain %s, actual: %s", precision, expected, sitemap.String())
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	sitemap := New()
+	sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/a"})
+	sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/b"})
+	capacity := cap(sitemap.items)
+
+	sitemap.Reset()
+	if sitemap.Len() != 0 {
+		t.Errorf("Expected reset sitemap to have %d items, actual: %d", 0, sitemap.Len())
+	}
+	if cap(sitemap.items) != capacity {
+		t.Errorf("Expected reset sitemap to keep capacity %d, actual: %d", capacity, cap(sitemap.items))
+	}
+	if sitemap.String() != New().String() {
+		t.Errorf("Expected reset sitemap to be %s, actual: %s", New().String(), sitemap.String())
+	}
+
+	if added, _ := sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/a"}); !added {
+		t.Errorf("Expected item removed by reset to be added again")
+	}
+}
+
+func TestToFileMissingDir(t *testing.T) {
+	dir := path.Join(t.TempDir(), "missing")
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	err := sitemap.ToFile(path.Join(dir, "sitemap.xml"))
+	if err == nil {
+		t.Fatalf("Expected saving to a missing directory to fail")
+	}
+	if !strings.Contains(err.Error(), "directory "+dir+" does not exist") {
+		t.Errorf("Expected error to name the missing directory, actual: %v", err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected error to wrap %v, actual: %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	sitemap := New()
+	sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/a"})
+	sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/admin/b"})
+	sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/c"})
+
+	sitemap.Filter(func(item SitemapItem) bool {
+		return !strings.Contains(item.Loc, "/admin/")
+	})
+
+	expected := New()
+	expected.Add(SitemapItem{Loc: "http://www.google.com/a"})
+	expected.Add(SitemapItem{Loc: "http://www.google.com/c"})
+	if sitemap.String() != expected.String() {
+		t.Errorf("Expected filtered sitemap to be %s, actual: %s", expected.String(), sitemap.String())
+	}
+	if sitemap.size != expected.size {
+		t.Errorf("Expected filtered sitemap to have size %d, actual: %d", expected.size, sitemap.size)
+	}
+
+	if added, _ := sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/admin/b"}); !added {
+		t.Errorf("Expected filtered out item to be added again")
+	}
+	if added, _ := sitemap.AddUnique(SitemapItem{Loc: "http://www.google.com/a"}); added {
+		t.Errorf("Expected kept item not to be added again")
+	}
+}
+
+func TestNewIndex(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	posts := New()
+	posts.Add(SitemapItem{Loc: "http://www.google.com/posts/1", LastMod: older})
+	posts.Add(SitemapItem{Loc: "http://www.google.com/posts/2", LastMod: newer})
+
+	pages := New()
+	pages.Add(SitemapItem{Loc: "http://www.google.com/about"})
+
+	index, err := NewIndex("http://www.google.com/sitemaps/", map[string]*Sitemap{
+		"posts.xml": posts,
+		"pages.xml": pages,
+	})
+	if err != nil {
+		t.Fatalf("could not create sitemap index: %v", err)
+	}
+
+	expected := []SitemapIndexItem{
+		{Loc: "http://www.google.com/sitemaps/pages.xml"},
+		{Loc: "http://www.google.com/sitemaps/posts.xml", LastMod: newer},
+	}
+	if !reflect.DeepEqual(index.items, expected) {
+		t.Errorf("Expected sitemap index items to be %v, actual: %v", expected, index.items)
+	}
+}
+
+func TestSize(t *testing.T) {
+	sitemap := New()
+	if sitemap.Size() != len(sitemap.String()) {
+		t.Errorf("Expected size of empty sitemap to be %d, actual: %d", len(sitemap.String()), sitemap.Size())
+	}
+
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com", LastMod: time.Now(), Priority: Priority(0.5)})
+	sitemap.Add(SitemapItem{
+		Loc:    "http://www.google.com/image",
+		Images: []Image{{Loc: "http://www.google.com/image.png"}},
+	})
+	if sitemap.Size() != len(sitemap.String()) {
+		t.Errorf("Expected size of sitemap to be %d, actual: %d", len(sitemap.String()), sitemap.Size())
+	}
+}
+
+func TestSetMaxItems(t *testing.T) {
+	sitemap := New()
+	sitemap.SetMaxItems(2)
+
+	for i := 0; i < 2; i++ {
+		if err := sitemap.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)}); err != nil {
+			t.Fatalf("could not add item %d: %v", i, err)
+		}
+	}
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com/2"}); err == nil {
+		t.Errorf("Expected adding beyond %d items to fail", 2)
+	}
+
+	other := New()
+	other.Add(SitemapItem{Loc: "http://www.google.com/3"})
+	if err := sitemap.Merge(other); err == nil {
+		t.Errorf("Expected merging beyond %d items to fail", 2)
+	}
+
+	sitemap.SetMaxItems(0)
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com/2"}); err != nil {
+		t.Errorf("Expected resetting the limit to allow %d items, actual: %v", MaxSitemapItems, err)
+	}
+}
+
+func TestDepthPriority(t *testing.T) {
+	tests := map[string]float32{
+		"http://www.google.com":                     1.0,
+		"http://www.google.com/":                    1.0,
+		"http://www.google.com/about":               0.9,
+		"http://www.google.com/blog/2024/01/post/":  0.6,
+		"http://www.google.com/a/b/c/d/e/f/g/h/i/j": 0.1,
+	}
+
+	for loc, expected := range tests {
+		if actual := DepthPriority(loc); actual != expected {
+			t.Errorf("Expected priority of %s to be %.1f, actual: %.1f", loc, expected, actual)
+		}
+	}
+}
+
+func TestSetPriorityFunc(t *testing.T) {
+	sitemap := New()
+	sitemap.SetPriorityFunc(DepthPriority)
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/about"})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/blog/post", Priority: Priority(0.3)})
+
+	if p := *sitemap.items[0].Priority; p != 0.9 {
+		t.Errorf("Expected computed priority to be %.1f, actual: %.1f", 0.9, p)
+	}
+	if p := *sitemap.items[1].Priority; p != 0.3 {
+		t.Errorf("Expected given priority to be kept as %.1f, actual: %.1f", 0.3, p)
+	}
+}
+
+func TestSetCompact(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a", Priority: Priority(0.5)})
+	sitemap.Add(SitemapItem{
+		Loc:    "http://www.google.com/b",
+		Images: []Image{{Loc: "http://www.google.com/b.png"}},
+	})
+	sitemap.SetCompact(true)
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?><urlset xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.sitemaps.org/schemas/sitemap/0.9 http://www.sitemaps.org/schemas/sitemap/0.9/sitemap.xsd" xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">` +
+		`<url><loc>http://www.google.com/a</loc><priority>0.5</priority></url>` +
+		`<url><loc>http://www.google.com/b</loc><image:image><image:loc>http://www.google.com/b.png</image:loc></image:image></url>` +
+		`</urlset>`
+	if sitemap.String() != expected {
+		t.Errorf("Expected compact sitemap to be %s, actual: %s", expected, sitemap.String())
+	}
+	if sitemap.Size() != len(expected) {
+		t.Errorf("Expected size of compact sitemap to be %d, actual: %d", len(expected), sitemap.Size())
+	}
+	if _, err := Parse(strings.NewReader(sitemap.String())); err != nil {
+		t.Errorf("Expected compact sitemap to parse, actual: %v", err)
+	}
+
+	sitemap.SetCompact(false)
+	if strings.Count(sitemap.String(), "\n") == 0 {
+		t.Errorf("Expected indented sitemap to span several lines, actual: %s", sitemap.String())
+	}
+}
+
+func TestAddAll(t *testing.T) {
+	sitemap := New()
+	err := sitemap.AddAll([]SitemapItem{
+		{Loc: "http://www.google.com/a"},
+		{Loc: "http://www.google.com/b"},
+	})
+	if err != nil {
+		t.Fatalf("could not add items: %v", err)
+	}
+	if sitemap.Len() != 2 {
+		t.Errorf("Expected sitemap to have %d items, actual: %d", 2, sitemap.Len())
+	}
+
+	err = sitemap.AddAll([]SitemapItem{
+		{Loc: "http://www.google.com/c"},
+		{Loc: "http://www.google.com/d", Priority: Priority(2)},
+		{Loc: "http://www.google.com/e"},
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "item 1:") {
+		t.Errorf("Expected error for item 1, actual: %v", err)
+	}
+	if sitemap.Len() != 3 {
+		t.Errorf("Expected sitemap to keep the items before the invalid one, actual: %d items", sitemap.Len())
+	}
+
+	sitemap.SetMaxItems(4)
+	err = sitemap.AddAll([]SitemapItem{
+		{Loc: "http://www.google.com/f"},
+		{Loc: "http://www.google.com/g"},
+	})
+	if err == nil {
+		t.Errorf("Expected adding beyond %d items to fail", 4)
+	}
+	if sitemap.Len() != 3 {
+		t.Errorf("Expected sitemap to have %d items after exceeding the limit, actual: %d", 3, sitemap.Len())
+	}
+}
+
+func TestEmptySitemap(t *testing.T) {
+	sitemap := New()
+
+	if err := sitemap.Validate(); err == nil {
+		t.Errorf("Expected empty sitemap to be invalid")
+	}
+
+	filename := path.Join(t.TempDir(), "sitemap.xml")
+	if err := sitemap.ToFile(filename); err == nil {
+		t.Errorf("Expected saving an empty sitemap to fail")
+	}
+	if _, err := os.Stat(filename); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected no file to be created for an empty sitemap, actual: %v", err)
+	}
+}
+
+func TestItems(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a"})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b"})
+
+	var locs []string
+	for item := range sitemap.Items() {
+		locs = append(locs, item.Loc)
+		sitemap.Add(SitemapItem{Loc: item.Loc + "/child"})
+	}
+	expected := []string{"http://www.google.com/a", "http://www.google.com/b"}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Expected items to be %v, actual: %v", expected, locs)
+	}
+
+	index := NewSitemapIndex()
+	index.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	items := index.Items()
+	index.items[0].Loc = "http://www.google.com/changed.xml"
+	for item := range items {
+		if item.Loc != "http://www.google.com/sitemap.xml" {
+			t.Errorf("Expected index item to be %s, actual: %s", "http://www.google.com/sitemap.xml", item.Loc)
+		}
+	}
+}
+
+func TestLatestMod(t *testing.T) {
+	sitemap := New()
+	if !sitemap.LatestMod().IsZero() {
+		t.Errorf("Expected latest lastmod of empty sitemap to be zero, actual: %v", sitemap.LatestMod())
+	}
+
+	latest := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a", LastMod: latest.AddDate(0, -1, 0)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b", LastMod: latest})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/c"})
+	if !sitemap.LatestMod().Equal(latest) {
+		t.Errorf("Expected latest lastmod to be %v, actual: %v", latest, sitemap.LatestMod())
+	}
+}
+
+func TestToXMLFileAndToGzipFile(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	if err := sitemap.ToXMLFile(path.Join(dir, "sitemap.xml.gz")); err != nil {
+		t.Fatalf("could not save sitemap: %v", err)
+	}
+	b, err := os.ReadFile(path.Join(dir, "sitemap.xml.gz"))
+	if err != nil {
+		t.Fatalf("could not read sitemap: %v", err)
+	}
+	if string(b) != sitemap.String() {
+		t.Errorf("Expected XML file to be uncompressed %s, actual: %s", sitemap.String(), b)
+	}
+
+	if err := sitemap.ToGzipFile(path.Join(dir, "sitemap.xml")); err != nil {
+		t.Fatalf("could not save gzipped sitemap: %v", err)
+	}
+	f, err := os.Open(path.Join(dir, "sitemap.xml.gz"))
+	if err != nil {
+		t.Fatalf("Expected .gz to be appended to the filename: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Expected file to be gzipped: %v", err)
+	}
+	b, err = io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("could not read gzipped sitemap: %v", err)
+	}
+	if string(b) != sitemap.String() {
+		t.Errorf("Expected gzipped file to be %s, actual: %s", sitemap.String(), b)
+	}
+}
+
+func TestValidateFutureLastMod(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/now", LastMod: time.Now().Add(time.Minute)})
+	if err := sitemap.Validate(); err != nil {
+		t.Errorf("Expected lastmod within the tolerance to be valid, got error: %v", err)
+	}
+
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/tomorrow", LastMod: time.Now().AddDate(0, 0, 1)})
+	err := sitemap.Validate()
+	if err == nil || !strings.Contains(err.Error(), "item 1: lastmod") || !strings.Contains(err.Error(), "http://www.google.com/tomorrow") {
+		t.Errorf("Expected error to report the future lastmod of item 1, actual: %v", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	sitemap := New()
+	sitemap.SetMaxItems(5)
+	sitemap.SetCompact(true)
+	for i := 0; i < 5; i++ {
+		sitemap.Add(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", i)})
+	}
+
+	chunks := sitemap.Split()
+	if len(chunks) != 1 || chunks[0].String() != sitemap.String() {
+		t.Errorf("Expected sitemap within the limits to be split into itself, actual: %d sitemaps", len(chunks))
+	}
+
+	sitemap.SetMaxItems(2)
+	chunks = sitemap.Split()
+	if len(chunks) != 3 {
+		t.Fatalf("Expected sitemap to be split into %d sitemaps, actual: %d", 3, len(chunks))
+	}
+
+	var locs []string
+	for _, chunk := range chunks {
+		if chunk.Len() > 2 {
+			t.Errorf("Expected split sitemaps to have at most %d items, actual: %d", 2, chunk.Len())
+		}
+		if !strings.Contains(chunk.String(), "><url>") {
+			t.Errorf("Expected split sitemaps to keep the settings of the sitemap, actual: %s", chunk.String())
+		}
+		for item := range chunk.Items() {
+			locs = append(locs, item.Loc)
+		}
+	}
+	for i, loc := range locs {
+		if expected := fmt.Sprintf("http://www.google.com/%d", i); loc != expected {
+			t.Errorf("Expected item %d of the split sitemaps to be %s, actual: %s", i, expected, loc)
+		}
+	}
+
+	if chunks := New().Split(); len(chunks) != 1 || chunks[0].Len() != 0 {
+		t.Errorf("Expected empty sitemap to be split into one empty sitemap")
+	}
+}
+
+func TestAddURL(t *testing.T) {
+	sitemap := New()
+	if err := sitemap.AddURL("http://www.google.com"); err != nil {
+		t.Fatalf("could not add url: %v", err)
+	}
+
+	expected := New()
+	expected.Add(SitemapItem{Loc: "http://www.google.com"})
+	if sitemap.String() != expected.String() {
+		t.Errorf("Expected sitemap to be %s, actual: %s", expected.String(), sitemap.String())
+	}
+
+	if err := sitemap.AddURL("/relative"); err == nil {
+		t.Errorf("Expected adding a relative url to fail")
+	}
+}
+
+func TestSitemapIndexAddChecked(t *testing.T) {
+	sitemapIndex := NewSitemapIndex()
+	if err := sitemapIndex.AddChecked(SitemapIndexItem{Loc: "sitemap.xml"}); !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected adding a relative loc to fail with %v, actual: %v", ErrInvalidLoc, err)
+	}
+
+	for i := 0; i < MaxSitemapIndexItems; i++ {
+		if err := sitemapIndex.AddChecked(SitemapIndexItem{Loc: fmt.Sprintf("http://www.google.com/sitemap-%d.xml", i)}); err != nil {
+			t.Fatalf("could not add sitemap %d: %v", i, err)
+		}
+	}
+	err := sitemapIndex.AddChecked(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	if !errors.Is(err, ErrMaxItemsExceeded) {
+		t.Errorf("Expected adding beyond %d sitemaps to fail with %v, actual: %v", MaxSitemapIndexItems, ErrMaxItemsExceeded, err)
+	}
+	if sitemapIndex.Len() != MaxSitemapIndexItems {
+		t.Errorf("Expected sitemap index to have %d sitemaps, actual: %d", MaxSitemapIndexItems, sitemapIndex.Len())
+	}
+}
+
+func TestSetOmitDeclaration(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	withDeclaration := sitemap.String()
+
+	sitemap.SetOmitDeclaration(true)
+	expected := strings.TrimPrefix(withDeclaration, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	if sitemap.String() != expected {
+		t.Errorf("Expected sitemap without declaration to be %s, actual: %s", expected, sitemap.String())
+	}
+	if sitemap.Size() != len(expected) {
+		t.Errorf("Expected size of sitemap without declaration to be %d, actual: %d", len(expected), sitemap.Size())
+	}
+
+	sitemap.SetCompact(true)
+	if !strings.HasPrefix(sitemap.String(), "<urlset ") {
+		t.Errorf("Expected compact sitemap without declaration to start with the urlset tag, actual: %s", sitemap.String())
+	}
+}
+
+// benchmarkSitemap returns a sitemap with the maximum number of items
+func benchmarkSitemap(b *testing.B) *Sitemap {
+	b.Helper()
+
+	lastMod := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sitemap := New()
+	for i := 0; i < MaxSitemapItems; i++ {
+		err := sitemap.Add(SitemapItem{
+			Loc:        fmt.Sprintf("http://www.google.com/page/%d?a=1&b=2", i),
+			LastMod:    lastMod,
+			ChangeFreq: ChangeFreqDaily,
+			Priority:   Priority(0.5),
+		})
+		if err != nil {
+			b.Fatalf("could not add item %d: %v", i, err)
+		}
+	}
+
+	return sitemap
+}
+
+func BenchmarkAdd(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkSitemap(b)
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	sitemap := benchmarkSitemap(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sitemap.WriteTo(io.Discard)
+	}
+}
+
+func TestFormatPlain(t *testing.T) {
+	lastMod := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	items := []SitemapItem{
+		{Loc: "http://www.google.com"},
+		{Loc: "http://www.google.com/?a=1&b=<2>&c=\"3\"&d='4'", LastMod: lastMod},
+		{Loc: "http://www.google.com/\t\r\n\x00caf\xc3\xa9\xff", ChangeFreq: ChangeFreqWeekly, Priority: Priority(0.25)},
+	}
+
+	for _, item := range items {
+		for _, compact := range []bool{false, true} {
+			var expected string
+			if compact {
+				b, _ := xml.Marshal(item.wire(time.RFC3339))
+				expected = string(b)
+			} else {
+				b, _ := xml.MarshalIndent(item.wire(time.RFC3339), "\t", "\t")
+				expected = "\n" + string(b)
+			}
+
+			if actual := item.formatPlain(time.RFC3339, compact); actual != expected {
+				t.Errorf("Expected item to be formatted as %q, actual: %q", expected, actual)
+			}
+		}
+	}
+}
+
+func TestItemFromFile(t *testing.T) {
+	filename := path.Join(t.TempDir(), "about.html")
+	if err := os.WriteFile(filename, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatalf("could not set modification time: %v", err)
+	}
+
+	item, err := ItemFromFile("http://www.google.com/about", filename)
+	if err != nil {
+		t.Fatalf("could not create item from file: %v", err)
+	}
+	if item.Loc != "http://www.google.com/about" || !item.LastMod.Equal(modTime) {
+		t.Errorf("Expected item to be %s with lastmod %v, actual: %s with lastmod %v", "http://www.google.com/about", modTime, item.Loc, item.LastMod)
+	}
+
+	if _, err := ItemFromFile("http://www.google.com/missing", path.Join(t.TempDir(), "missing.html")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected error for a missing file to be %v, actual: %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestSitemapItemValidate(t *testing.T) {
+	if err := (SitemapItem{Loc: "http://www.google.com", ChangeFreq: ChangeFreqDaily, Priority: Priority(1)}).Validate(); err != nil {
+		t.Errorf("Expected item to be valid, got error: %v", err)
+	}
+
+	err := SitemapItem{Loc: "/relative", ChangeFreq: "dialy", Priority: Priority(-0.1)}.Validate()
+	for _, target := range []error{ErrInvalidLoc, ErrInvalidChangeFreq, ErrInvalidPriority} {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected error to report %v, actual: %v", target, err)
+		}
+	}
+}
+
+func TestSitemapIndexSetBaseURL(t *testing.T) {
+	sitemapIndex := NewSitemapIndex()
+	if err := sitemapIndex.SetBaseURL("/sitemaps/"); err == nil {
+		t.Errorf("Expected setting a relative base URL to fail")
+	}
+	if err := sitemapIndex.SetBaseURL("http://www.google.com/sitemaps/"); err != nil {
+		t.Fatalf("could not set base URL: %v", err)
+	}
+
+	sitemapIndex.Add(SitemapIndexItem{Loc: "sitemap-1.xml"})
+	sitemapIndex.AddChecked(SitemapIndexItem{Loc: "/sitemap-2.xml"})
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.example.com/sitemap.xml"})
+
+	expected := []string{
+		"http://www.google.com/sitemaps/sitemap-1.xml",
+		"http://www.google.com/sitemap-2.xml",
+		"http://www.example.com/sitemap.xml",
+	}
+	for i, loc := range expected {
+		if sitemapIndex.items[i].Loc != loc {
+			t.Errorf("Expected loc of sitemap %d to be %s, actual: %s", i, loc, sitemapIndex.items[i].Loc)
+		}
+	}
+
+	dir := t.TempDir()
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	if err := sitemap.ToFile(path.Join(dir, "sitemap.xml")); err != nil {
+		t.Fatalf("could not save sitemap: %v", err)
+	}
+	sitemapIndex, err := NewIndexFromDirOptions(context.Background(), dir, DirOptions{
+		PathPrefix: "sitemaps/",
+		BaseURL:    "http://www.google.com/",
+	})
+	if err != nil {
+		t.Fatalf("could not create sitemap index: %v", err)
+	}
+	if loc := sitemapIndex.items[0].Loc; loc != "http://www.google.com/sitemaps/sitemap.xml" {
+		t.Errorf("Expected loc to be %s, actual: %s", "http://www.google.com/sitemaps/sitemap.xml", loc)
+	}
+}
+
+func TestSitemapIndexSplit(t *testing.T) {
+	sitemapIndex := NewSitemapIndex()
+	sitemapIndex.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	if chunks := sitemapIndex.Split(); len(chunks) != 1 || chunks[0].String() != sitemapIndex.String() {
+		t.Errorf("Expected sitemap index within the limit to be split into itself, actual: %d indexes", len(chunks))
+	}
+
+	for i := 1; i <= MaxSitemapIndexItems; i++ {
+		sitemapIndex.Add(SitemapIndexItem{Loc: fmt.Sprintf("http://www.google.com/sitemap-%d.xml", i)})
+	}
+	chunks := sitemapIndex.Split()
+	if len(chunks) != 2 {
+		t.Fatalf("Expected sitemap index to be split into %d indexes, actual: %d", 2, len(chunks))
+	}
+	if chunks[0].Len() != MaxSitemapIndexItems || chunks[1].Len() != 1 {
+		t.Errorf("Expected split indexes to have %d and %d sitemaps, actual: %d and %d", MaxSitemapIndexItems, 1, chunks[0].Len(), chunks[1].Len())
+	}
+	if loc := chunks[1].items[0].Loc; loc != fmt.Sprintf("http://www.google.com/sitemap-%d.xml", MaxSitemapIndexItems) {
+		t.Errorf("Expected last split index to hold the last sitemap, actual: %s", loc)
+	}
+
+	if chunks := NewSitemapIndex().Split(); len(chunks) != 1 || chunks[0].Len() != 0 {
+		t.Errorf("Expected empty sitemap index to be split into one empty index")
+	}
+}
+
+func TestToHashedFile(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	filename, err := sitemap.ToHashedFile(path.Join(dir, "sitemap.xml.gz"))
+	if err != nil {
+		t.Fatalf("could not save hashed sitemap: %v", err)
+	}
+	if matched, _ := filepath.Match(path.Join(dir, "sitemap-????????.xml.gz"), filename); !matched {
+		t.Errorf("Expected filename to have a hash before the extension, actual: %s", filename)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Errorf("Expected hashed file to exist: %v", err)
+	}
+
+	same, _ := sitemap.ToHashedFile(path.Join(dir, "sitemap.xml.gz"))
+	if same != filename {
+		t.Errorf("Expected the same content to get the same filename %s, actual: %s", filename, same)
+	}
+
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/about"})
+	changed, _ := sitemap.ToHashedFile(path.Join(dir, "sitemap.xml.gz"))
+	if changed == filename {
+		t.Errorf("Expected changed content to get a new filename, actual: %s", changed)
+	}
+
+	if _, err := sitemap.ToHashedFile(path.Join(dir, "sitemap.txt")); err == nil {
+		t.Errorf("Expected saving to a file without .xml extension to fail")
+	}
+}
+
+func TestWriteChunkedHashed(t *testing.T) {
+	dir := t.TempDir()
+
+	index, err := WriteChunkedHashed([]SitemapItem{{Loc: "http://www.google.com"}}, dir, "http://www.google.com/", false)
+	if err != nil {
+		t.Fatalf("could not write chunked sitemaps: %v", err)
+	}
+
+	loc := index.items[0].Loc
+	if matched, _ := path.Match("http://www.google.com/sitemap-1-????????.xml", loc); !matched {
+		t.Errorf("Expected index to reference the hashed filename, actual: %s", loc)
+	}
+	if _, err := os.Stat(path.Join(dir, path.Base(loc))); err != nil {
+		t.Errorf("Expected hashed file to exist: %v", err)
+	}
+}
+
+func TestSetAllowedSchemes(t *testing.T) {
+	sitemap := New()
+	if err := sitemap.Add(SitemapItem{Loc: "ftp://www.google.com/file"}); !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected ftp loc to be rejected by default, actual: %v", err)
+	}
+
+	sitemap.SetAllowedSchemes("HTTPS")
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com"}); !errors.Is(err, ErrInvalidLoc) {
+		t.Errorf("Expected http loc to be rejected, actual: %v", err)
+	}
+	if err := sitemap.Add(SitemapItem{Loc: "https://www.google.com"}); err != nil {
+		t.Errorf("Expected https loc to be added, actual: %v", err)
+	}
+
+	sitemap.SetAllowedSchemes()
+	if err := sitemap.Add(SitemapItem{Loc: "ftp://www.google.com/file"}); err != nil {
+		t.Errorf("Expected any scheme to be allowed, actual: %v", err)
+	}
+
+	sitemap.SetAllowedSchemes("https")
+	if err := sitemap.Validate(); !errors.Is(err, ErrInvalidLoc) || !strings.Contains(err.Error(), "ftp://www.google.com/file") {
+		t.Errorf("Expected Validate to report the ftp loc, actual: %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	sitemap := New()
+	sitemap.SetCompact(true)
+	sitemap.Add(SitemapItem{
+		Loc:      "http://www.google.com",
+		Priority: Priority(0.5),
+		Images:   []Image{{Loc: "http://www.google.com/image.png"}},
+	})
+
+	clone := sitemap.Clone()
+	if clone.String() != sitemap.String() {
+		t.Fatalf("Expected clone to be %s, actual: %s", sitemap, clone)
+	}
+
+	*clone.items[0].Priority = 0.1
+	clone.items[0].Images[0].Loc = "http://www.google.com/other.png"
+	clone.Add(SitemapItem{Loc: "http://www.google.com/about"})
+	if p := *sitemap.items[0].Priority; p != 0.5 {
+		t.Errorf("Expected priority of the original to be %.1f, actual: %.1f", 0.5, p)
+	}
+	if loc := sitemap.items[0].Images[0].Loc; loc != "http://www.google.com/image.png" {
+		t.Errorf("Expected image of the original to be unchanged, actual: %s", loc)
+	}
+	if sitemap.Len() != 1 {
+		t.Errorf("Expected original to have %d item, actual: %d", 1, sitemap.Len())
+	}
+
+	index := NewSitemapIndex()
+	index.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap.xml"})
+	indexClone := index.Clone()
+	indexClone.items[0].Loc = "http://www.google.com/other.xml"
+	if loc := index.items[0].Loc; loc != "http://www.google.com/sitemap.xml" {
+		t.Errorf("Expected loc of the original index to be unchanged, actual: %s", loc)
+	}
+}
+
+func TestSitemapIndexSetCompact(t *testing.T) {
+	index := NewSitemapIndex()
+	index.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap-1.xml"})
+	index.Add(SitemapIndexItem{Loc: "http://www.google.com/sitemap-2.xml", LastMod: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)})
+	index.SetCompact(true)
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?><sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<sitemap><loc>http://www.google.com/sitemap-1.xml</loc></sitemap>` +
+		`<sitemap><loc>http://www.google.com/sitemap-2.xml</loc><lastmod>2024-01-02T00:00:00Z</lastmod></sitemap>` +
+		`</sitemapindex>`
+	if index.String() != expected {
+		t.Errorf("Expected compact sitemap index to be %s, actual: %s", expected, index.String())
+	}
+	if _, err := ParseIndex(strings.NewReader(index.String())); err != nil {
+		t.Errorf("Expected compact sitemap index to parse, actual: %v", err)
+	}
+
+	index.SetCompact(false)
+	if strings.Count(index.String(), "\n") == 0 {
+		t.Errorf("Expected indented sitemap index to span several lines, actual: %s", index.String())
+	}
+}
+
+func TestAddBounded(t *testing.T) {
+	sitemap := New()
+	sitemap.SetMaxItems(3)
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	for _, d := range []int{3, 1, 5, 2, 4} {
+		if _, err := sitemap.AddBounded(SitemapItem{Loc: fmt.Sprintf("http://www.google.com/%d", d), LastMod: day(d)}); err != nil {
+			t.Fatalf("could not add item %d: %v", d, err)
+		}
+	}
+
+	var locs []string
+	for item := range sitemap.Items() {
+		locs = append(locs, item.Loc)
+	}
+	expected := []string{"http://www.google.com/5", "http://www.google.com/4", "http://www.google.com/3"}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Expected items to be %v, actual: %v", expected, locs)
+	}
+
+	added, err := sitemap.AddBounded(SitemapItem{Loc: "http://www.google.com/0", LastMod: day(1)})
+	if added || err != nil {
+		t.Errorf("Expected item older than all items not to be added, actual: %t, %v", added, err)
+	}
+
+	if _, err := sitemap.AddBounded(SitemapItem{Loc: "/relative", LastMod: day(9)}); err == nil {
+		t.Errorf("Expected invalid item to fail")
+	}
+	if sitemap.Len() != 3 {
+		t.Errorf("Expected failed item not to evict an item, actual length: %d", sitemap.Len())
+	}
+
+	size := sitemap.Size()
+	sitemap.resize()
+	if sitemap.Size() != size {
+		t.Errorf("Expected size to be %d, actual: %d", sitemap.Size(), size)
+	}
+}
+
+func TestSitemapIndexAddSitemap(t *testing.T) {
+	latest := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a", LastMod: latest.AddDate(0, -1, 0)})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b", LastMod: latest})
+
+	index := NewSitemapIndex()
+	if err := index.SetBaseURL("http://www.google.com/sitemaps/"); err != nil {
+		t.Fatalf("could not set base URL: %v", err)
+	}
+	index.AddSitemap("sitemap-1.xml", sitemap)
+
+	item := index.items[0]
+	if item.Loc != "http://www.google.com/sitemaps/sitemap-1.xml" {
+		t.Errorf("Expected loc to be resolved against the base URL, actual: %s", item.Loc)
+	}
+	if !item.LastMod.Equal(latest) {
+		t.Errorf("Expected lastmod to be %s, actual: %s", latest, item.LastMod)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/a"})
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/b"})
+
+	modified := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !sitemap.Touch("http://www.google.com/b", modified) {
+		t.Fatalf("Expected item to be found")
+	}
+	if !sitemap.items[1].LastMod.Equal(modified) {
+		t.Errorf("Expected LastMod to be %s, actual: %s", modified, sitemap.items[1].LastMod)
+	}
+	if sitemap.Touch("http://www.google.com/c", modified) {
+		t.Errorf("Expected missing item not to be found")
+	}
+
+	sitemap.Sort()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com/0"})
+	sitemap.SortByLastMod()
+	if !sitemap.Touch("http://www.google.com/a", modified.AddDate(0, 0, 1)) {
+		t.Fatalf("Expected item to be found after sorting")
+	}
+	for item := range sitemap.Items() {
+		if item.Loc == "http://www.google.com/a" && !item.LastMod.Equal(modified.AddDate(0, 0, 1)) {
+			t.Errorf("Expected the LastMod of %s to be updated, actual: %s", item.Loc, item.LastMod)
+		}
+	}
+
+	size := sitemap.Size()
+	sitemap.resize()
+	if sitemap.Size() != size {
+		t.Errorf("Expected size to be %d, actual: %d", sitemap.Size(), size)
+	}
+}
+
+func TestNewIndexFromDirVerify(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	for _, name := range []string{"sitemap-1.xml", "sitemap-2.xml.gz"} {
+		if err := sitemap.ToFile(path.Join(dir, name)); err != nil {
+			t.Fatalf("could not save sitemap %s: %v", name, err)
+		}
+	}
+	truncated := sitemap.String()[:len(sitemap.String())/2]
+	if err := os.WriteFile(path.Join(dir, "sitemap-3.xml"), []byte(truncated), 0644); err != nil {
+		t.Fatalf("could not write truncated sitemap: %v", err)
+	}
+
+	opts := DirOptions{PathPrefix: "http://www.google.com/", Verify: true}
+	if _, err := NewIndexFromDirOptions(context.Background(), dir, opts); err == nil || !strings.Contains(err.Error(), "sitemap-3.xml") {
+		t.Errorf("Expected truncated sitemap to be reported, actual: %v", err)
+	}
+
+	opts.SkipInvalid = true
+	index, err := NewIndexFromDirOptions(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatalf("could not create sitemap index from directory: %v", err)
+	}
+	if index.Len() != 2 {
+		t.Errorf("Expected index to have %d sitemaps, actual: %d", 2, index.Len())
+	}
+}
+
+func TestSplitByLanguage(t *testing.T) {
+	items := []SitemapItem{
+		{Loc: "http://www.google.com/en/", Alternates: []Alternate{{Hreflang: "de", Href: "http://www.google.com/de/"}}},
+		{Loc: "http://www.google.com/de/"},
+		{Loc: "http://www.google.com/en/about"},
+	}
+
+	sitemaps, err := SplitByLanguage(items, func(item SitemapItem) string {
+		return strings.Split(item.Loc, "/")[3]
+	})
+	if err != nil {
+		t.Fatalf("could not split sitemap: %v", err)
+	}
+
+	if len(sitemaps) != 2 || sitemaps["en"].Len() != 2 || sitemaps["de"].Len() != 1 {
+		t.Fatalf("Expected 2 items in en and 1 in de, actual: %v", sitemaps)
+	}
+	if strings.Contains(sitemaps["en"].String(), "xhtml:link") {
+		t.Errorf("Expected alternates to be left out, actual: %s", sitemaps["en"])
+	}
+	if len(items[0].Alternates) != 1 {
+		t.Errorf("Expected the alternates of the given items to be kept")
+	}
+}
+
+func TestSetLenientChangeFreq(t *testing.T) {
+	sitemap := New()
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com", ChangeFreq: "fortnightly"}); !errors.Is(err, ErrInvalidChangeFreq) {
+		t.Errorf("Expected unknown changefreq to be rejected by default, actual: %v", err)
+	}
+
+	sitemap.SetLenientChangeFreq(true)
+	if err := sitemap.Add(SitemapItem{Loc: "http://www.google.com", ChangeFreq: "fortnightly"}); err != nil {
+		t.Errorf("Expected unknown changefreq to be accepted, actual: %v", err)
+	}
+	if err := sitemap.Validate(); err != nil {
+		t.Errorf("Expected lenient sitemap to be valid, actual: %v", err)
+	}
+
+	sitemap.SetLenientChangeFreq(false)
+	if err := sitemap.Validate(); !errors.Is(err, ErrInvalidChangeFreq) {
+		t.Errorf("Expected strict Validate to report the changefreq, actual: %v", err)
+	}
+}
+
+func TestNewIndexFromDirProgress(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+	for _, name := range []string{"sitemap-1.xml", "sitemap-2.xml", "sitemap-3.xml.gz"} {
+		if err := sitemap.ToFile(path.Join(dir, name)); err != nil {
+			t.Fatalf("could not save sitemap %s: %v", name, err)
+		}
+	}
+
+	var calls [][2]int
+	_, err := NewIndexFromDirOptions(context.Background(), dir, DirOptions{
+		PathPrefix: "http://www.google.com/",
+		Progress: func(processed, total int) {
+			calls = append(calls, [2]int{processed, total})
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not create sitemap index from directory: %v", err)
+	}
+
+	expected := [][2]int{{1, 3}, {2, 3}, {3, 3}}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected progress to be %v, actual: %v", expected, calls)
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":       {ModTime: modTime},
+		"about.html":       {ModTime: modTime},
+		"blog/index.html":  {ModTime: modTime},
+		"blog/post-1.html": {ModTime: modTime},
+		"style.css":        {ModTime: modTime},
+	}
+
+	sitemap, err := FromFS(fsys, "http://www.google.com/", nil)
+	if err != nil {
+		t.Fatalf("could not create sitemap from fs: %v", err)
+	}
+
+	var locs []string
+	for item := range sitemap.Items() {
+		locs = append(locs, item.Loc)
+		if !item.LastMod.Equal(modTime) {
+			t.Errorf("Expected LastMod of %s to be %s, actual: %s", item.Loc, modTime, item.LastMod)
+		}
+	}
+	expected := []string{"http://www.google.com/about.html", "http://www.google.com/blog/", "http://www.google.com/blog/post-1.html", "http://www.google.com/"}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Expected locs to be %v, actual: %v", expected, locs)
+	}
+
+	sitemap, err = FromFS(fsys, "http://www.google.com/site", nil)
+	if err != nil {
+		t.Fatalf("could not create sitemap from fs: %v", err)
+	}
+	locs = nil
+	for item := range sitemap.Items() {
+		locs = append(locs, item.Loc)
+	}
+	expected = []string{"http://www.google.com/site/about.html", "http://www.google.com/site/blog/", "http://www.google.com/site/blog/post-1.html", "http://www.google.com/site/"}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Expected locs without a trailing slash on the base URL to be %v, actual: %v", expected, locs)
+	}
+
+	sitemap, err = FromFS(fsys, "http://www.google.com/", func(p string) bool { return strings.HasSuffix(p, ".css") })
+	if err != nil {
+		t.Fatalf("could not create sitemap from fs: %v", err)
+	}
+	if sitemap.Len() != 1 {
+		t.Errorf("Expected include to select %d file, actual: %d", 1, sitemap.Len())
+	}
+}
+
+func TestToFileAuto(t *testing.T) {
+	dir := t.TempDir()
+
+	sitemap := New()
+	sitemap.Add(SitemapItem{Loc: "http://www.google.com"})
+
+	tests := []struct {
+		path      string
+		threshold int
+		expected  string
+	}{
+		{"small.xml.gz", sitemap.Size(), "small.xml"},
+		{"large.xml", sitemap.Size() - 1, "large.xml.gz"},
+	}
+
+	for _, test := range tests {
+		written, err := sitemap.ToFileAuto(filepath.Join(dir, test.path), test.threshold)
+		if err != nil {
+			t.Fatalf("could not save sitemap: %v", err)
+		}
+		if written != filepath.Join(dir, test.expected) {
+			t.Errorf("Expected sitemap to be written to %s, actual: %s", test.expected, written)
+		}
+
+		file, err := os.Open(written)
+		if err != nil {
+			t.Fatalf("could not open %s: %v", written, err)
+		}
+		if _, err := Parse(file); err != nil {
+			t.Errorf("Expected %s to parse, actual: %v", written, err)
+		}
+		file.Close()
+	}
+
+	if _, err := sitemap.ToFileAuto(filepath.Join(dir, "sitemap.txt"), 0); !errors.Is(err, ErrInvalidExtension) {
+		t.Errorf("Expected invalid extension to fail, actual: %v", err)
+	}
+}
+
+func TestMergeDir(t *testing.T) {
+	dir := t.TempDir()
+
+	first := New()
+	first.Add(SitemapItem{Loc: "http://www.google.com/a"})
+	first.Add(SitemapItem{Loc: "http://www.google.com/b"})
+	second := New()
+	second.Add(SitemapItem{Loc: "http://www.google.com/b"})
+	second.Add(SitemapItem{Loc: "http://www.google.com/c"})
+
+	files := map[string]*Sitemap{
+		"sitemap-1.xml":     first,
+		"sitemap-2.xml.gz":  second,
+		"sitemap-index.xml": second,
+	}
+	for name, sitemap := range files {
+		if err := sitemap.ToFile(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("could not save sitemap %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *"), 0644); err != nil {
+		t.Fatalf("could not write robots.txt: %v", err)
+	}
+
+	sitemap, err := MergeDir(dir)
+	if err != nil {
+		t.Fatalf("could not merge directory: %v", err)
+	}
+
+	var locs []string
+	for item := range sitemap.Items() {
+		locs = append(locs, item.Loc)
+	}
+	expected := []string{"http://www.google.com/a", "http://www.google.com/b", "http://www.google.com/c"}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Expected locs to be %v, actual: %v", expected, locs)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "sitemap-3.xml"), []byte("<urlset>"), 0644); err != nil {
+		t.Fatalf("could not write broken sitemap: %v", err)
+	}
+	if _, err := MergeDir(dir); err == nil || !strings.Contains(err.Error(), "sitemap-3.xml") {
+		t.Errorf("Expected broken sitemap to be reported, actual: %v", err)
+	}
+}
+
+func TestAddSizeWithNamespaces(t *testing.T) {
+	item := SitemapItem{
+		Loc:    "http://www.google.com",
+		Images: []Image{{Loc: "http://www.google.com/image.png"}},
+	}
+
+	probe := New()
+	probe.AddNamespace("big", "http://www.google.com/")
+	probe.Add(item)
+	padding := MaxUncompressedBytes - probe.Size()
+
+	for _, test := range []struct {
+		padding int
+		fits    bool
+	}{
+		{padding, true},
+		{padding + 1, false},
+	} {
+		sitemap := New()
+		if err := sitemap.AddNamespace("big", "http://www.google.com/"+strings.Repeat("a", test.padding)); err != nil {
+			t.Fatalf("could not add namespace: %v", err)
+		}
+
+		err := sitemap.Add(item)
+		if test.fits && err != nil {
+			t.Errorf("Expected item to fit exactly, actual: %v", err)
+		}
+		if !test.fits && !errors.Is(err, ErrMaxSizeExceeded) {
+			t.Errorf("Expected item to exceed the maximum size by one byte, actual: %v", err)
+		}
+		if test.fits && sitemap.Validate() != nil {
+			t.Errorf("Expected sitemap of %d bytes to be valid, actual: %v", sitemap.Size(), sitemap.Validate())
+		}
+	}
+}
+
+func TestSplitKeepsItemsFailingCurrentRules(t *testing.T) {
+	lenient := New()
+	lenient.SetLenientChangeFreq(true)
+	lenient.Add(SitemapItem{Loc: "http://www.google.com/custom", ChangeFreq: "custom"})
+
+	sitemap := New()
+	sitemap.SetAllowedSchemes()
+	sitemap.SetPriorityFunc(DepthPriority)
+	sitemap.Add(SitemapItem{Loc: "ftp://www.google.com/file"})
+	if err := sitemap.Merge(lenient); err != nil {
+		t.Fatalf("could not merge sitemap: %v", err)
+	}
+	sitemap.SetAllowedSchemes("https")
+	sitemap.SetPriorityFunc(func(string) float32 { return 0.1 })
+
+	var items []SitemapItem
+	for _, chunk := range sitemap.Split() {
+		for item := range chunk.Items() {
+			items = append(items, item)
+		}
+	}
+	expected := []SitemapItem{
+		{Loc: "ftp://www.google.com/file", Priority: Priority(0.9)},
+		{Loc: "http://www.google.com/custom", ChangeFreq: "custom"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Expected split sitemaps to hold %v as they are, actual: %v", expected, items)
+	}
+}
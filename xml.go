@@ -0,0 +1,446 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The types below are the XML representations of the exported types, with
+// the values formatted as the protocol expects them. xmlURL and the types of
+// the extensions are used to encode sitemaps, their tags name the elements
+// with the prefixes the urlset tag declares. encoding/xml matches decoded
+// elements by namespace URI instead, so xmlParsedURL is used to decode them.
+// xmlSitemapIndex and xmlSitemap have no prefixes and are used both ways.
+
+// xmlURL is the url element of a sitemap
+type xmlURL struct {
+	XMLName    xml.Name    `xml:"url"`
+	Loc        string      `xml:"loc"`
+	LastMod    string      `xml:"lastmod,omitempty"`
+	ChangeFreq string      `xml:"changefreq,omitempty"`
+	Priority   string      `xml:"priority,omitempty"`
+	Images     []Image     `xml:"image:image"`
+	Videos     []Video     `xml:"video:video"`
+	News       *NewsInfo   `xml:"news:news"`
+	Alternates []Alternate `xml:"xhtml:link"`
+	Mobile     *struct{}   `xml:"mobile:mobile"`
+	Geo        *GeoInfo    `xml:"geo:geo"`
+	Extra      string      `xml:",innerxml"`
+}
+
+// xmlParsedURL is the url element of a parsed sitemap. The elements of the
+// extensions are matched by their namespace URI, whatever their prefix.
+type xmlParsedURL struct {
+	Loc        string           `xml:"loc"`
+	LastMod    string           `xml:"lastmod"`
+	ChangeFreq string           `xml:"changefreq"`
+	Priority   string           `xml:"priority"`
+	Images     []xmlParsedImage `xml:"http://www.google.com/schemas/sitemap-image/1.1 image"`
+	Videos     []xmlParsedVideo `xml:"http://www.google.com/schemas/sitemap-video/1.1 video"`
+	News       *xmlParsedNews   `xml:"http://www.google.com/schemas/sitemap-news/0.9 news"`
+	Links      []xmlLink        `xml:"http://www.w3.org/1999/xhtml link"`
+	Mobile     *struct{}        `xml:"http://www.google.com/schemas/sitemap-mobile/1.0 mobile"`
+	Geo        *xmlParsedGeo    `xml:"http://www.google.com/geo/schemas/sitemap/1.0 geo"`
+}
+
+// xmlParsedImage is the image:image element of a parsed url
+type xmlParsedImage struct {
+	Loc         string `xml:"loc"`
+	Caption     string `xml:"caption"`
+	GeoLocation string `xml:"geo_location"`
+	Title       string `xml:"title"`
+	License     string `xml:"license"`
+}
+
+// xmlParsedVideo is the video:video element of a parsed url
+type xmlParsedVideo struct {
+	ThumbnailLoc    string `xml:"thumbnail_loc"`
+	Title           string `xml:"title"`
+	Description     string `xml:"description"`
+	ContentLoc      string `xml:"content_loc"`
+	PlayerLoc       string `xml:"player_loc"`
+	Duration        string `xml:"duration"`
+	PublicationDate string `xml:"publication_date"`
+}
+
+// xmlParsedNews is the news:news element of a parsed url
+type xmlParsedNews struct {
+	PublicationName     string `xml:"publication>name"`
+	PublicationLanguage string `xml:"publication>language"`
+	PublicationDate     string `xml:"publication_date"`
+	Title               string `xml:"title"`
+}
+
+// xmlParsedGeo is the geo:geo element of a parsed url
+type xmlParsedGeo struct {
+	Format string `xml:"format"`
+}
+
+// xmlSitemapIndex is the sitemapindex element of a sitemap index
+type xmlSitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Sitemaps []xmlSitemap `xml:"sitemap"`
+}
+
+// xmlSitemap is the sitemap element of a sitemap index
+type xmlSitemap struct {
+	XMLName xml.Name `xml:"sitemap"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+// xmlVideo is the video:video element of a url
+type xmlVideo struct {
+	ThumbnailLoc    string `xml:"video:thumbnail_loc,omitempty"`
+	Title           string `xml:"video:title"`
+	Description     string `xml:"video:description"`
+	ContentLoc      string `xml:"video:content_loc,omitempty"`
+	PlayerLoc       string `xml:"video:player_loc,omitempty"`
+	Duration        int64  `xml:"video:duration,omitempty"`
+	PublicationDate string `xml:"video:publication_date,omitempty"`
+}
+
+// xmlNews is the news:news element of a url
+type xmlNews struct {
+	PublicationName     string `xml:"news:publication>news:name"`
+	PublicationLanguage string `xml:"news:publication>news:language"`
+	PublicationDate     string `xml:"news:publication_date"`
+	Title               string `xml:"news:title"`
+}
+
+// xmlLink is the xhtml:link element of a url
+type xmlLink struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// wire returns the XML representation of the item, with LastMod formatted
+// with layout
+func (i *SitemapItem) wire(layout string) xmlURL {
+	u := xmlURL{
+		Loc:        i.Loc,
+		LastMod:    formatTime(i.LastMod, layout),
+		Images:     i.Images,
+		Videos:     i.Videos,
+		News:       i.News,
+		Alternates: i.Alternates,
+		Geo:        i.Geo,
+		Extra:      i.Extra,
+	}
+
+	if i.Mobile {
+		u.Mobile = &struct{}{}
+	}
+
+	if i.News == nil {
+		u.ChangeFreq = i.ChangeFreq
+		if i.Priority != nil {
+			u.Priority = fmt.Sprintf("%.1f", *i.Priority)
+		}
+	}
+
+	return u
+}
+
+// item converts the decoded url element to a SitemapItem
+func (u *xmlParsedURL) item(strict bool) (SitemapItem, error) {
+	item := SitemapItem{
+		Loc:        strings.TrimSpace(u.Loc),
+		ChangeFreq: strings.TrimSpace(u.ChangeFreq),
+		Mobile:     u.Mobile != nil,
+	}
+
+	lastMod, err := parseLastMod(u.LastMod, strict)
+	if err != nil {
+		return item, fmt.Errorf("invalid lastmod of %s: %v", item.Loc, err)
+	}
+	item.LastMod = lastMod
+
+	if priority := strings.TrimSpace(u.Priority); priority != "" {
+		p, err := strconv.ParseFloat(priority, 32)
+		if err != nil {
+			return item, fmt.Errorf("invalid priority of %s: %v", item.Loc, err)
+		}
+		item.Priority = Priority(float32(p))
+	}
+
+	for _, image := range u.Images {
+		item.Images = append(item.Images, Image{
+			Loc:         strings.TrimSpace(image.Loc),
+			Caption:     image.Caption,
+			GeoLocation: image.GeoLocation,
+			Title:       image.Title,
+			License:     strings.TrimSpace(image.License),
+		})
+	}
+
+	for _, video := range u.Videos {
+		v, err := video.video(strict)
+		if err != nil {
+			return item, fmt.Errorf("invalid video of %s: %v", item.Loc, err)
+		}
+		item.Videos = append(item.Videos, v)
+	}
+
+	if u.News != nil {
+		publicationDate, err := parseLastMod(u.News.PublicationDate, strict)
+		if err != nil {
+			return item, fmt.Errorf("invalid news publication date of %s: %v", item.Loc, err)
+		}
+		item.News = &NewsInfo{
+			PublicationName:     u.News.PublicationName,
+			PublicationLanguage: strings.TrimSpace(u.News.PublicationLanguage),
+			PublicationDate:     publicationDate,
+			Title:               u.News.Title,
+		}
+	}
+
+	for _, link := range u.Links {
+		if link.Rel != "alternate" {
+			continue
+		}
+		item.Alternates = append(item.Alternates, Alternate{
+			Hreflang: link.Hreflang,
+			Href:     strings.TrimSpace(link.Href),
+		})
+	}
+
+	if u.Geo != nil {
+		item.Geo = &GeoInfo{Format: strings.TrimSpace(u.Geo.Format)}
+	}
+
+	return item, nil
+}
+
+// video converts the decoded video:video element to a Video
+func (v *xmlParsedVideo) video(strict bool) (Video, error) {
+	video := Video{
+		ThumbnailLoc: strings.TrimSpace(v.ThumbnailLoc),
+		Title:        v.Title,
+		Description:  v.Description,
+		ContentLoc:   strings.TrimSpace(v.ContentLoc),
+		PlayerLoc:    strings.TrimSpace(v.PlayerLoc),
+	}
+
+	if duration := strings.TrimSpace(v.Duration); duration != "" {
+		seconds, err := strconv.ParseInt(duration, 10, 64)
+		if err != nil {
+			return video, fmt.Errorf("invalid duration: %v", err)
+		}
+		video.Duration = time.Duration(seconds) * time.Second
+	}
+
+	publicationDate, err := parseLastMod(v.PublicationDate, strict)
+	if err != nil {
+		return video, fmt.Errorf("invalid publication date: %v", err)
+	}
+	video.PublicationDate = publicationDate
+
+	return video, nil
+}
+
+// LastModLayouts are the layouts a lastmod is parsed with, in order. They
+// are the W3C datetime formats the protocol allows, and can be changed to
+// accept other formats.
+var LastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	time.DateOnly,
+	"2006-01",
+	"2006",
+}
+
+// ParseLastMod parses value with the first of LastModLayouts that it
+// matches and returns that layout too
+func ParseLastMod(value string) (time.Time, string, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range LastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, layout, nil
+		}
+	}
+
+	return time.Time{}, "", fmt.Errorf("%q does not match any of the lastmod layouts", value)
+}
+
+// parseLastMod parses a decoded lastmod with ParseLastMod. A lastmod that
+// matches none of the layouts is left out, unless strict is set.
+func parseLastMod(value string, strict bool) (time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}, nil
+	}
+
+	t, _, err := ParseLastMod(value)
+	if err != nil && strict {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// wire returns the XML representation of the item
+func (i *SitemapIndexItem) wire() xmlSitemap {
+	return xmlSitemap{
+		Loc:     i.Loc,
+		LastMod: formatTime(i.LastMod, time.RFC3339),
+	}
+}
+
+// item converts the decoded sitemap element to a SitemapIndexItem
+func (s *xmlSitemap) item(strict bool) (SitemapIndexItem, error) {
+	item := SitemapIndexItem{
+		Loc: strings.TrimSpace(s.Loc),
+	}
+
+	lastMod, err := parseLastMod(s.LastMod, strict)
+	if err != nil {
+		return item, fmt.Errorf("invalid lastmod of %s: %v", item.Loc, err)
+	}
+	item.LastMod = lastMod
+
+	return item, nil
+}
+
+// wire returns the XML representation of the video
+func (v *Video) wire() xmlVideo {
+	return xmlVideo{
+		ThumbnailLoc:    v.ThumbnailLoc,
+		Title:           v.Title,
+		Description:     v.Description,
+		ContentLoc:      v.ContentLoc,
+		PlayerLoc:       v.PlayerLoc,
+		Duration:        int64(v.Duration / time.Second),
+		PublicationDate: formatTime(v.PublicationDate, time.RFC3339),
+	}
+}
+
+// wire returns the XML representation of the news info
+func (n *NewsInfo) wire() xmlNews {
+	return xmlNews{
+		PublicationName:     n.PublicationName,
+		PublicationLanguage: n.PublicationLanguage,
+		PublicationDate:     n.PublicationDate.Format(time.RFC3339),
+		Title:               n.Title,
+	}
+}
+
+// wire returns the XML representation of the alternate
+func (a *Alternate) wire() xmlLink {
+	return xmlLink{
+		Rel:      "alternate",
+		Hreflang: a.Hreflang,
+		Href:     a.Href,
+	}
+}
+
+// plain reports whether the item uses none of the extensions, so that
+// formatPlain can be used instead of encoding/xml
+func (i *SitemapItem) plain() bool {
+	return len(i.Images) == 0 && len(i.Videos) == 0 && i.News == nil && len(i.Alternates) == 0 &&
+		!i.Mobile && i.Geo == nil && i.Extra == ""
+}
+
+// formatPlain returns the same as format for an item without extensions,
+// without the reflection of encoding/xml, which is the bulk of the work of
+// writing a large sitemap
+func (i *SitemapItem) formatPlain(layout string, compact bool) string {
+	u := i.wire(layout)
+
+	var b strings.Builder
+	b.Grow(len(u.Loc) + len(u.LastMod) + len(u.ChangeFreq) + 128)
+
+	indent, nested, end := "\n\t", "\n\t\t", "\n\t"
+	if compact {
+		indent, nested, end = "", "", ""
+	}
+
+	b.WriteString(indent)
+	b.WriteString("<url>")
+	for _, elem := range [...]struct{ name, value string }{
+		{"loc", u.Loc},
+		{"lastmod", u.LastMod},
+		{"changefreq", u.ChangeFreq},
+		{"priority", u.Priority},
+	} {
+		if elem.value == "" && elem.name != "loc" {
+			continue
+		}
+
+		b.WriteString(nested)
+		b.WriteByte('<')
+		b.WriteString(elem.name)
+		b.WriteByte('>')
+		escapeString(&b, elem.value)
+		b.WriteString("</")
+		b.WriteString(elem.name)
+		b.WriteByte('>')
+	}
+	b.WriteString(end)
+	b.WriteString("</url>")
+
+	return b.String()
+}
+
+// escapeString writes s to b escaped like encoding/xml escapes character
+// data
+func escapeString(b *strings.Builder, s string) {
+	last := 0
+	for i := 0; i < len(s); {
+		r, width := utf8.DecodeRuneInString(s[i:])
+		i += width
+
+		var esc string
+		switch r {
+		case '"':
+			esc = "&#34;"
+		case '\'':
+			esc = "&#39;"
+		case '&':
+			esc = "&amp;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '\t':
+			esc = "&#x9;"
+		case '\n':
+			esc = "&#xA;"
+		case '\r':
+			esc = "&#xD;"
+		default:
+			if !inCharacterRange(r) || (r == utf8.RuneError && width == 1) {
+				esc = "\uFFFD"
+				break
+			}
+			continue
+		}
+
+		b.WriteString(s[last : i-width])
+		b.WriteString(esc)
+		last = i
+	}
+	b.WriteString(s[last:])
+}
+
+// inCharacterRange reports whether r is allowed in XML character data
+func inCharacterRange(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// formatTime formats t with layout, or returns an empty string for the zero
+// time so that the element is left out
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(layout)
+}